@@ -13,6 +13,7 @@ import (
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
 	"github.com/urfave/cli"
 )
 
@@ -22,6 +23,17 @@ func globalFlags() []cli.Flag {
 			Name:  "verbose",
 			Usage: "specify to enable verbose mode",
 		},
+		cli.StringFlag{
+			Name:   "overlap",
+			Value:  "warn",
+			Usage:  "specify how to treat a task that overlaps others. one of warn, reject or trim",
+			EnvVar: "KKZM_OVERLAP",
+		},
+		cli.StringFlag{
+			Name:   "tz",
+			Usage:  "specify time zone to bucket summaries by day, week and month (e.g. Asia/Tokyo)",
+			EnvVar: "KKZM_TZ",
+		},
 	}
 }
 
@@ -36,6 +48,27 @@ func commands() []cli.Command {
 					Name:  "s, stop",
 					Usage: "stop all on-going kizami in advance",
 				},
+				cli.StringFlag{
+					Name:  "at",
+					Value: "now",
+					Usage: "specify started time (e.g. '15m ago', 9:30, 'yesterday 17:00', 'mon 10:00')",
+				},
+			},
+		},
+		{
+			Name:      "add",
+			Usage:     "add task that has been already done",
+			ArgsUsage: "[desc]",
+			Action:    CmdAdd,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "specify started time (e.g. '1h ago', 9:30, 'yesterday 17:00', 'mon 10:00')",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "specify stopped time (e.g. '15m ago', 10:15, 'yesterday 18:00', 'mon 11:00')",
+				},
 			},
 		},
 		{
@@ -58,11 +91,86 @@ func commands() []cli.Command {
 			Name:   "list",
 			Usage:  "show list of tasks",
 			Action: CmdList,
+			Flags: []cli.Flag{
+				formatFlag,
+				cli.StringFlag{
+					Name: "template",
+					Usage: "specify Go template or name of saved template to show each task " +
+						"(e.g. '{{.ID}} {{.Desc}} {{.Elapsed | hours}}'). see README for fields and functions",
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "show tasks running at or after specified time (e.g. yesterday, '2h ago', 2019-05-01)",
+				},
+				cli.StringFlag{
+					Name:  "until",
+					Usage: "show tasks started before specified time",
+				},
+				cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "show tasks that have specified tag. prefix with '!' to exclude (e.g. --tag code --tag '!meeting')",
+				},
+				cli.StringFlag{
+					Name:  "grep",
+					Usage: "show tasks whose description contains specified text, case-insensitively",
+				},
+				cli.BoolFlag{
+					Name:  "running",
+					Usage: "show only running tasks",
+				},
+				cli.IntFlag{
+					Name:  "n",
+					Usage: "show at most specified number of tasks. the latest ones are shown unless --sort is specified",
+				},
+				cli.IntFlag{
+					Name:  "offset",
+					Usage: "skip specified number of tasks",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "sort tasks by one of id, started, stopped, elapsed or desc",
+				},
+				cli.BoolFlag{
+					Name:  "reverse, r",
+					Usage: "sort tasks in descending order",
+				},
+			},
+		},
+		{
+			Name:      "search",
+			Usage:     "search tasks by description. phrase (\"foo bar\") and prefix (foo*) queries are supported",
+			ArgsUsage: "[query]",
+			Action:    CmdSearch,
+			Flags: []cli.Flag{
+				formatFlag,
+				cli.IntFlag{
+					Name:  "n",
+					Value: 20,
+					Usage: "show at most specified number of tasks. 0 shows all",
+				},
+			},
 		},
 		{
 			Name:   "stop",
 			Usage:  "stop task",
 			Action: CmdStop,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "at",
+					Value: "now",
+					Usage: "specify stopped time (e.g. '15m ago', 9:30, 'yesterday 17:00', 'mon 10:00')",
+				},
+			},
+		},
+		{
+			Name:   "pause",
+			Usage:  "pause task",
+			Action: CmdPause,
+		},
+		{
+			Name:   "resume",
+			Usage:  "resume paused task",
+			Action: CmdResume,
 		},
 		{
 			Name:   "delete",
@@ -71,16 +179,55 @@ func commands() []cli.Command {
 		},
 		{
 			Name:   "summary",
-			Usage:  "show summary of specified month",
+			Usage:  "show summary of specified term",
 			Action: CmdSummary,
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "m, month",
-					Value: thisMonth(),
-					Usage: "specify year and month to show summary",
+					Usage: "specify year and month (yyyy-mm) to show summary. this month if omitted",
+				},
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "specify first date (yyyy-mm-dd) of term to show summary",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "specify last date (yyyy-mm-dd) of term to show summary. today if omitted",
+				},
+				cli.BoolFlag{
+					Name:  "week",
+					Usage: "show summary of this week",
+				},
+				cli.BoolFlag{
+					Name:  "day",
+					Usage: "show summary of today",
+				},
+				cli.StringFlag{
+					Name:  "per",
+					Usage: "split summary into each period. one of day, week or month",
+				},
+				cli.BoolFlag{
+					Name:  "include-running",
+					Usage: "count on-going kizamis up to now. they are marked with '*'",
+				},
+				cli.StringFlag{
+					Name:  "attribution",
+					Value: "full",
+					Usage: "specify how to attribute elapsed time of kizami that has multiple tags. " +
+						"one of full (to each tag), split (evenly) or primary (first tag only)",
+				},
+				cli.IntFlag{
+					Name:  "depth",
+					Usage: "roll hierarchical tags up to specified depth (e.g. #client/acme/backend is summarized as #client/acme by 2)",
 				},
+				formatFlag,
 			},
 		},
+		{
+			Name:   "check",
+			Usage:  "report overlapped tasks and tasks stopped before started",
+			Action: CmdCheck,
+		},
 		{
 			Name:   "tags",
 			Usage:  "show list of tags",
@@ -90,6 +237,165 @@ func commands() []cli.Command {
 					Name:  "id",
 					Usage: "show tags of specified task",
 				},
+				cli.BoolFlag{
+					Name:  "count",
+					Usage: "show number of tasks tagged with each tag",
+				},
+				cli.BoolFlag{
+					Name:  "elapsed",
+					Usage: "show total elapsed time of tasks tagged with each tag",
+				},
+				formatFlag,
+			},
+		},
+		{
+			Name:  "tag",
+			Usage: "manage tags. descriptions of tasks are rewritten accordingly",
+			Subcommands: []cli.Command{
+				{
+					Name:      "rename",
+					Usage:     "rename a tag. it is merged if the new tag exists already",
+					ArgsUsage: "[tag] [new tag]",
+					Action:    CmdTagRename,
+				},
+				{
+					Name:      "merge",
+					Usage:     "merge tags into a tag",
+					ArgsUsage: "[tags...] --into [tag]",
+					Action:    CmdTagMerge,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "into",
+							Usage: "specify tag to merge into",
+						},
+					},
+				},
+				{
+					Name:      "rm",
+					Usage:     "remove a tag. '#' of the tag is removed from descriptions of tasks",
+					ArgsUsage: "[tag]",
+					Action:    CmdTagRemove,
+				},
+			},
+		},
+		{
+			Name:  "template",
+			Usage: "manage named templates for list command",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "show named templates",
+					Action: CmdTemplateList,
+				},
+				{
+					Name:      "save",
+					Usage:     "save a named template",
+					ArgsUsage: "[name] [template]",
+					Action:    CmdTemplateSave,
+				},
+				{
+					Name:      "rm",
+					Usage:     "remove a named template",
+					ArgsUsage: "[name]",
+					Action:    CmdTemplateRemove,
+				},
+			},
+		},
+		{
+			Name:  "db",
+			Usage: "manage database",
+			Subcommands: []cli.Command{
+				{
+					Name:   "migrate",
+					Usage:  "apply pending schema migrations",
+					Action: CmdDBMigrate,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "status",
+							Usage: "show status of migrations without applying them",
+						},
+					},
+				},
+				{
+					Name:   "gc",
+					Usage:  "remove relations and intervals of deleted tasks and tags",
+					Action: CmdDBGC,
+				},
+			},
+		},
+		{
+			Name:   "export",
+			Usage:  "export all tasks and tags",
+			Action: CmdExport,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format, to",
+					Value: "json",
+					Usage: "specify format to export. one of json, timewarrior or ics",
+				},
+				cli.StringFlag{
+					Name: "o, output",
+					Usage: "specify file to write. standard output if omitted. " +
+						"for timewarrior, specify directory to write data files of each month",
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "export tasks running at or after specified time to ics (e.g. yesterday, '2h ago', 2019-05-01)",
+				},
+				cli.StringFlag{
+					Name:  "until",
+					Usage: "export tasks started before specified time to ics",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrite existing data files of timewarrior",
+				},
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "import tasks and tags exported by export command or other tools",
+			ArgsUsage: "[file or directory]",
+			Action:    CmdImport,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format, from",
+					Value: "json",
+					Usage: "specify format to import. one of json, csv, ics (standard input if file is omitted) " +
+						"or timewarrior (directory of data files. ~/.timewarrior/data if omitted)",
+				},
+				cli.StringFlag{
+					Name:  "preset",
+					Usage: "specify column mapping of csv. one of toggl or clockify",
+				},
+				cli.StringSliceFlag{
+					Name: "column",
+					Usage: "specify a column of csv as key=header, overriding preset. " +
+						"key is one of desc, start_date, start_time, end_date, end_time, duration or tags " +
+						"(comma separated headers, e.g. tags=Project,Tags)",
+				},
+				cli.StringSliceFlag{
+					Name:  "layout",
+					Usage: "specify layout of Go's time package to parse start and end of csv, date and time joined with a space",
+				},
+				cli.StringFlag{
+					Name:  "source-tz",
+					Usage: "specify time zone of dates and times in csv, or floating times in ics. --tz or local time zone if omitted",
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "import events of ics started at or after specified time (e.g. yesterday, '2h ago', 2019-05-01)",
+				},
+				cli.StringFlag{
+					Name:  "until",
+					Usage: "import events of ics started before specified time. now if omitted",
+				},
+				cli.StringFlag{
+					Name:  "mode",
+					Value: "merge",
+					Usage: "specify how to import. merge (skip tasks that have same description and started time) " +
+						"or replace (delete all tasks and tags before import)",
+				},
 			},
 		},
 	}
@@ -103,10 +409,6 @@ func CommandNotFound(c *cli.Context, command string) {
 	os.Exit(2)
 }
 
-func thisMonth() string {
-	return time.Now().Format("2006-01")
-}
-
 func round(d, r time.Duration) time.Duration {
 	if r <= 0 {
 		return d
@@ -126,13 +428,19 @@ func round(d, r time.Duration) time.Duration {
 	return d
 }
 
-func toString(k *kokizami.Kizami) string {
-	var stoppedAt string
-	if k.StoppedAt.Unix() == 0 {
-		stoppedAt = "*" + time.Now().In(time.Local).Format("2006-01-02 15:04:05")
-	} else {
-		stoppedAt = k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
+func stoppedAtString(k *kokizami.Kizami) string {
+	switch {
+	case k.Paused():
+		return "(paused)"
+	case k.StoppedAt.Unix() == 0:
+		return "*" + time.Now().In(time.Local).Format("2006-01-02 15:04:05")
+	default:
+		return k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
 	}
+}
+
+func toString(k *kokizami.Kizami) string {
+	stoppedAt := stoppedAtString(k)
 
 	return strconv.Itoa(k.ID) + "\t" +
 		k.Desc + "\t" +
@@ -142,12 +450,7 @@ func toString(k *kokizami.Kizami) string {
 }
 
 func toStringArray(k *kokizami.Kizami) []string {
-	var stoppedAt string
-	if k.StoppedAt.Unix() == 0 {
-		stoppedAt = "*" + time.Now().In(time.Local).Format("2006-01-02 15:04:05")
-	} else {
-		stoppedAt = k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
-	}
+	stoppedAt := stoppedAtString(k)
 
 	return []string{
 		strconv.Itoa(k.ID),
@@ -192,51 +495,61 @@ func CmdStart(c *cli.Context) error {
 		return fmt.Errorf("start needs one arguments [desc]")
 	}
 
-	kkzm := kkzm(c)
+	at, err := kokizami.ParseTime(c.String("at"), time.Now())
+	if err != nil {
+		return err
+	}
 
-	if c.GlobalBool("stop") {
-		err := kkzm.StopAll()
-		if err != nil {
-			return err
+	var k *kokizami.Kizami
+	err = kkzm(c).Transaction(func(tx *kokizami.Kokizami) error {
+		if c.Bool("stop") {
+			err := tx.StopAllAt(at)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	k, err := kkzm.Start(desc)
+		k, err = tx.StartAt(desc, at)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	fmt.Println(toString(k))
 
-	return tagging(kkzm, k.ID, desc)
+	return nil
 }
 
-func tagging(kkzm *kokizami.Kokizami, kizamiID int, desc string) error {
-	// remove all tags from specified kizami first
-	err := kkzm.Untagging(kizamiID)
-	if err != nil {
-		return err
+// CmdAdd adds a task that has been already done
+// kokizami add [desc] --from [started_at] --to [stopped_at]
+func CmdAdd(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("add needs one arguments [desc]")
 	}
-
-	tags := extractTagsFromString(desc)
-	if len(tags) == 0 {
-		return nil
+	if !c.IsSet("from") || !c.IsSet("to") {
+		return fmt.Errorf("add needs --from and --to")
 	}
 
-	err = kkzm.AddTags(tags)
+	now := time.Now()
+	startedAt, err := kokizami.ParseTime(c.String("from"), now)
 	if err != nil {
 		return err
 	}
-
-	tagIDs := make([]int, len(tags))
-	ts, err := kkzm.TagsByLabels(tags)
+	stoppedAt, err := kokizami.ParseTime(c.String("to"), now)
 	if err != nil {
 		return err
 	}
-	for i, v := range ts {
-		tagIDs[i] = v.ID
+
+	kkzm := kkzm(c)
+
+	k, err := kkzm.Add(args[0], startedAt, stoppedAt)
+	if err != nil {
+		return err
 	}
+	fmt.Println(toString(k))
 
-	return kkzm.Tagging(kizamiID, tagIDs)
+	return nil
 }
 
 // CmdRestart starts a task from old task list
@@ -272,7 +585,7 @@ func CmdRestart(c *cli.Context) error {
 	}
 	fmt.Println(toString(k))
 
-	return tagging(kkzm, k.ID, k.Desc)
+	return nil
 }
 
 // CmdEdit edits a specified task
@@ -302,11 +615,36 @@ func CmdEdit(c *cli.Context) error {
 // CmdList shows kokizami list
 // kokizami list
 func CmdList(c *cli.Context) error {
-	l, err := kkzm(c).List()
+	format, err := outputFormat(c)
 	if err != nil {
 		return err
 	}
 
+	kkzm := kkzm(c)
+	l, err := listKizamis(c, kkzm)
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet("template") {
+		if format != "text" {
+			return fmt.Errorf("--template can not be used with --format")
+		}
+		return listWithTemplate(c, kkzm, l)
+	}
+
+	if format != "text" {
+		tags, err := tagsOf(kkzm, l)
+		if err != nil {
+			return err
+		}
+		rs := make([]record, len(l))
+		for i, v := range l {
+			rs[i] = newKizamiRecord(v, tags[v.ID])
+		}
+		return writeRecords(c.App.Writer, format, kizamiHeader, rs)
+	}
+
 	if len(l) == 0 {
 		fmt.Println("list is empty")
 		return nil
@@ -331,14 +669,192 @@ func CmdList(c *cli.Context) error {
 	return nil
 }
 
+var listQueryFlags = []string{"since", "until", "tag", "grep", "running", "n", "offset", "sort", "reverse"}
+
+var sortOrders = map[string]kokizami.KizamiOrder{
+	"id":      kokizami.OrderByID,
+	"started": kokizami.OrderByStartedAt,
+	"stopped": kokizami.OrderByStoppedAt,
+	"elapsed": kokizami.OrderByElapsed,
+	"desc":    kokizami.OrderByDesc,
+}
+
+// listKizamis returns kizamis filtered, sorted and paged by flags of list command
+func listKizamis(c *cli.Context, kkzm *kokizami.Kokizami) ([]*kokizami.Kizami, error) {
+	query := false
+	for _, v := range listQueryFlags {
+		query = query || c.IsSet(v)
+	}
+	if !query {
+		return kkzm.List()
+	}
+
+	now := time.Now()
+	q := kokizami.KizamiQuery{
+		Desc:       c.String("grep"),
+		Running:    c.Bool("running"),
+		Limit:      c.Int("n"),
+		Offset:     c.Int("offset"),
+		Descending: c.Bool("reverse"),
+		Now:        now,
+	}
+
+	var err error
+	if c.IsSet("since") {
+		q.Since, err = kokizami.ParseTime(c.String("since"), now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.IsSet("until") {
+		q.Until, err = kokizami.ParseTime(c.String("until"), now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, v := range c.StringSlice("tag") {
+		exclude := strings.HasPrefix(v, "!")
+		v, err = tagLabel(strings.TrimPrefix(v, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if exclude {
+			q.ExcludeTags = append(q.ExcludeTags, v)
+		} else {
+			q.Tags = append(q.Tags, v)
+		}
+	}
+
+	if c.IsSet("sort") {
+		order, ok := sortOrders[c.String("sort")]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort key [%s]. one of id, started, stopped, elapsed or desc is available", c.String("sort"))
+		}
+		q.OrderBy = order
+		return kkzm.Find(q)
+	}
+
+	if q.Limit == 0 {
+		return kkzm.Find(q)
+	}
+
+	// take the latest tasks, and show them in ascending order unless --reverse is specified
+	q.Descending = true
+	l, err := kkzm.Find(q)
+	if err != nil || c.Bool("reverse") {
+		return l, err
+	}
+	for i, j := 0, len(l)-1; i < j; i, j = i+1, j-1 {
+		l[i], l[j] = l[j], l[i]
+	}
+	return l, nil
+}
+
+// tagsOf returns tags of each of specified kizamis
+func tagsOf(kkzm *kokizami.Kokizami, ks []*kokizami.Kizami) (map[int][]*kokizami.Tag, error) {
+	ids := make([]int, len(ks))
+	for i, v := range ks {
+		ids[i] = v.ID
+	}
+	return kkzm.TagsByKizamiIDs(ids)
+}
+
+func listWithTemplate(c *cli.Context, kkzm *kokizami.Kokizami, l []*kokizami.Kizami) error {
+	conf, err := loadConfig(configPath(c))
+	if err != nil {
+		return err
+	}
+
+	t, err := parseListTemplate(c.String("template"), conf)
+	if err != nil {
+		return err
+	}
+
+	tags, err := tagsOf(kkzm, l)
+	if err != nil {
+		return err
+	}
+	items := make([]*listItem, len(l))
+	for i, v := range l {
+		items[i] = newListItem(v, tags[v.ID])
+	}
+
+	return executeListTemplate(c.App.Writer, t, items)
+}
+
+// CmdSearch shows tasks whose desc matches query ordered by relevance
+// kokizami search [query]
+func CmdSearch(c *cli.Context) error {
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args()) == 0 {
+		return fmt.Errorf("specify query to search")
+	}
+
+	kkzm := kkzm(c)
+	rs, err := kkzm.Search(strings.Join(c.Args(), " "), c.Int("n"))
+	if err != nil {
+		return err
+	}
+
+	if format != "text" {
+		ks := make([]*kokizami.Kizami, len(rs))
+		for i, v := range rs {
+			ks[i] = v.Kizami
+		}
+		tags, err := tagsOf(kkzm, ks)
+		if err != nil {
+			return err
+		}
+		records := make([]record, len(rs))
+		for i, v := range rs {
+			records[i] = newKizamiRecord(v.Kizami, tags[v.Kizami.ID])
+		}
+		return writeRecords(c.App.Writer, format, kizamiHeader, records)
+	}
+
+	if len(rs) == 0 {
+		fmt.Fprintln(c.App.Writer, "no task matched")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(c.App.Writer)
+	table.SetBorders(tablewriter.Border{
+		Left:   true,
+		Top:    false,
+		Right:  true,
+		Bottom: false,
+	})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+
+	for _, v := range rs {
+		row := toStringArray(v.Kizami)
+		row[1] = v.Snippet
+		table.Append(row)
+	}
+	table.Render()
+
+	return nil
+}
+
 // CmdStop update specified task's stopped_at
 // kokizami stop      ... stop all tasks they don't have stopped_at
 // kokizami stop [id] ... stop a task by specified id
 func CmdStop(c *cli.Context) error {
+	at, err := kokizami.ParseTime(c.String("at"), time.Now())
+	if err != nil {
+		return err
+	}
+
 	args := c.Args()
 	switch len(args) {
 	case 0:
-		err := kkzm(c).StopAll()
+		err := kkzm(c).StopAllAt(at)
 		if err != nil {
 			return err
 		}
@@ -347,7 +863,7 @@ func CmdStop(c *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		err = kkzm(c).Stop(id)
+		err = kkzm(c).StopAt(id, at)
 		if err != nil {
 			return err
 		}
@@ -357,6 +873,41 @@ func CmdStop(c *cli.Context) error {
 	return nil
 }
 
+// CmdPause pauses specified task
+// kokizami pause      ... pause all on-going tasks
+// kokizami pause [id] ... pause a task by specified id
+func CmdPause(c *cli.Context) error {
+	args := c.Args()
+	switch len(args) {
+	case 0:
+		return kkzm(c).PauseAll()
+	case 1:
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		return kkzm(c).Pause(id)
+	default:
+		return fmt.Errorf("pause needs at most one arguments [id]")
+	}
+}
+
+// CmdResume resumes specified paused task
+// kokizami resume [id]
+func CmdResume(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("resume needs one arguments [id]")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	return kkzm(c).Resume(id)
+}
+
 // CmdDelete deletes specified task
 // kokizami delete [id]
 func CmdDelete(c *cli.Context) error {
@@ -418,21 +969,19 @@ func editTaskWithEditor(kkzm *kokizami.Kokizami, id int) (*kokizami.Kizami, erro
 }
 
 func edit(kkzm *kokizami.Kokizami, k *kokizami.Kizami, id int, desc, start, stop string) (*kokizami.Kizami, error) {
-	startedAt, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local)
+	now := time.Now()
+	startedAt, err := kokizami.ParseTime(start, now)
 	if err != nil {
 		return nil, err
 	}
 
-	if stop == "-" {
-		t, err := time.Parse("2006-01-02 15:04:05", "1970-01-01 00:00:00")
+	// "-" means that the task is on-going
+	stoppedAt := time.Unix(0, 0)
+	if strings.TrimSpace(stop) != "-" {
+		stoppedAt, err = kokizami.ParseTime(stop, now)
 		if err != nil {
 			return nil, err
 		}
-		stop = t.Local().Format("2006-01-02 15:04:05")
-	}
-	stoppedAt, err := time.ParseInLocation("2006-01-02 15:04:05", stop, time.Local)
-	if err != nil {
-		return nil, err
 	}
 
 	k.ID = id
@@ -440,12 +989,7 @@ func edit(kkzm *kokizami.Kokizami, k *kokizami.Kizami, id int, desc, start, stop
 	k.StartedAt = startedAt
 	k.StoppedAt = stoppedAt
 
-	ret, err := kkzm.Edit(k)
-	if err != nil {
-		return nil, err
-	}
-
-	return ret, tagging(kkzm, k.ID, desc)
+	return kkzm.Edit(k)
 }
 
 func editTextWithEditor(prewrite string) (string, error) {
@@ -488,13 +1032,25 @@ func runEditor(filename string) error {
 type descSummary struct {
 	desc        string
 	descElapsed time.Duration
+	inProgress  bool
 }
 
 type tagSummary struct {
 	tagElapsed    time.Duration
+	inProgress    bool
 	descSummaries []*descSummary
 }
 
+// elapsedString returns elapsed time as string.
+// elapsed time that includes on-going kizamis is marked with "*".
+func elapsedString(d time.Duration, inProgress bool) string {
+	s := round(d, time.Second).String()
+	if inProgress {
+		return "*" + s
+	}
+	return s
+}
+
 type tagSummaries map[string]*tagSummary
 
 func (s tagSummaries) String() string {
@@ -512,52 +1068,258 @@ func (s tagSummaries) String() string {
 		if v != "" {
 			tag = v
 		}
-		fmt.Fprintf(buf, "%s\t%s\n", tag, s[v].tagElapsed)
+		fmt.Fprintf(buf, "%s\t%s\n", tag, elapsedString(s[v].tagElapsed, s[v].inProgress))
 
 		for _, d := range s[v].descSummaries {
-			fmt.Fprintf(buf, "  %s\t%s\n", d.desc, d.descElapsed)
+			fmt.Fprintf(buf, "  %s\t%s\n", d.desc, elapsedString(d.descElapsed, d.inProgress))
 		}
 	}
 	return buf.String()
 }
 
-// CmdSummary shows summary of elapsed time of specified month
-func CmdSummary(c *cli.Context) error {
-	yyyymm := c.String("month")
-	tags, err := kkzm(c).SummaryByTag(yyyymm)
-	if err != nil {
-		return err
+// summaryRange returns the range to summarize and its label
+// from flags of summary command. The month of now in Location of kkzm
+// is summarized if no term is specified.
+func summaryRange(kkzm *kokizami.Kokizami, c *cli.Context, now time.Time) (from, to time.Time, label string, err error) {
+	loc := time.Local
+	if kkzm.Location != nil {
+		loc = kkzm.Location
+	}
+
+	switch {
+	case c.IsSet("from") || c.IsSet("to"):
+		if !c.IsSet("from") {
+			return from, to, "", fmt.Errorf("--to needs --from")
+		}
+		from, err = time.ParseInLocation("2006-01-02", c.String("from"), loc)
+		if err != nil {
+			return from, to, "", fmt.Errorf("invalid argument format. should be yyyy-mm-dd: %v", err)
+		}
+
+		last := now.In(loc)
+		if c.IsSet("to") {
+			last, err = time.ParseInLocation("2006-01-02", c.String("to"), loc)
+			if err != nil {
+				return from, to, "", fmt.Errorf("invalid argument format. should be yyyy-mm-dd: %v", err)
+			}
+		}
+		// the last date is inclusive
+		_, to = kkzm.Range(kokizami.PeriodDay, last)
+		label = from.Format("2006-01-02") + " - " + last.Format("2006-01-02")
+	case c.Bool("day"):
+		from, to = kkzm.Range(kokizami.PeriodDay, now)
+		label = from.Format("2006-01-02")
+	case c.Bool("week"):
+		from, to = kkzm.Range(kokizami.PeriodWeek, now)
+		label = from.Format("2006-01-02") + " - " + to.AddDate(0, 0, -1).Format("2006-01-02")
+	default:
+		yyyymm := now.In(loc).Format("2006-01")
+		if c.IsSet("month") {
+			yyyymm = c.String("month")
+		}
+		m, err := time.ParseInLocation("2006-01", yyyymm, loc)
+		if err != nil {
+			return from, to, "", fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+		}
+		from, to = kkzm.Range(kokizami.PeriodMonth, m)
+		label = yyyymm
+	}
+
+	return from, to, label, nil
+}
+
+func parsePeriod(s string) (kokizami.Period, error) {
+	switch s {
+	case "day":
+		return kokizami.PeriodDay, nil
+	case "week":
+		return kokizami.PeriodWeek, nil
+	case "month":
+		return kokizami.PeriodMonth, nil
+	}
+	return 0, fmt.Errorf("invalid period [%s]. should be one of day, week or month", s)
+}
+
+func parseAttribution(s string) (kokizami.Attribution, error) {
+	switch s {
+	case "full":
+		return kokizami.AttributionFull, nil
+	case "split":
+		return kokizami.AttributionSplit, nil
+	case "primary":
+		return kokizami.AttributionPrimary, nil
 	}
+	return 0, fmt.Errorf("invalid attribution [%s]. should be one of full, split or primary", s)
+}
 
+func newTagSummaries(tags, descs []*kokizami.Elapsed) tagSummaries {
 	summaries := tagSummaries{}
 	for _, v := range tags {
 		summaries[v.Tag] = &tagSummary{
 			tagElapsed: v.Elapsed,
+			inProgress: v.InProgress,
 		}
 	}
 
-	descs, err := kkzm(c).SummaryByDesc(yyyymm)
-	if err != nil {
-		return err
-	}
-
 	for _, v := range descs {
+		if summaries[v.Tag] == nil {
+			summaries[v.Tag] = &tagSummary{}
+		}
 		summaries[v.Tag].descSummaries = append(summaries[v.Tag].descSummaries, &descSummary{
 			desc:        v.Desc,
 			descElapsed: v.Elapsed,
+			inProgress:  v.InProgress,
 		})
 	}
 
-	fmt.Printf("Summary of %s\n%s\n", yyyymm, summaries)
+	return summaries
+}
+
+// CmdSummary shows summary of elapsed time of specified term
+func CmdSummary(c *cli.Context) error {
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	kkzm := kkzm(c)
+
+	from, to, label, err := summaryRange(kkzm, c, time.Now())
+	if err != nil {
+		return err
+	}
+
+	a, err := parseAttribution(c.String("attribution"))
+	if err != nil {
+		return err
+	}
+
+	if c.Int("depth") < 0 {
+		return fmt.Errorf("depth (%d) must not be negative", c.Int("depth"))
+	}
+
+	opts := []kokizami.SummaryOption{kokizami.WithAttribution(a), kokizami.WithDepth(c.Int("depth"))}
+	if c.Bool("include-running") {
+		opts = append(opts, kokizami.IncludeRunning())
+	}
+
+	if c.IsSet("per") {
+		return summaryPerPeriod(c, kkzm, format, from, to, opts)
+	}
+
+	tags, err := kkzm.SummaryByTag(from, to, opts...)
+	if err != nil {
+		return err
+	}
+
+	descs, err := kkzm.SummaryByDesc(from, to, opts...)
+	if err != nil {
+		return err
+	}
+
+	total, err := kkzm.SummaryTotal(from, to, opts...)
+	if err != nil {
+		return err
+	}
+
+	if format != "text" {
+		return writeRecords(c.App.Writer, format, summaryHeader, newSummaryRecords(from, to, tags, descs, total))
+	}
+
+	fmt.Printf("Summary of %s\n%s\nTotal\t%s\n", label, newTagSummaries(tags, descs),
+		elapsedString(total.Elapsed, total.InProgress))
 	return nil
 }
 
+func summaryPerPeriod(c *cli.Context, kkzm *kokizami.Kokizami, format string, from, to time.Time, opts []kokizami.SummaryOption) error {
+	p, err := parsePeriod(c.String("per"))
+	if err != nil {
+		return err
+	}
+
+	tags, err := kkzm.SummaryByTagPerPeriod(from, to, p, opts...)
+	if err != nil {
+		return err
+	}
+
+	descs, err := kkzm.SummaryByDescPerPeriod(from, to, p, opts...)
+	if err != nil {
+		return err
+	}
+
+	rs := []record{}
+	for i := range tags {
+		total, err := kkzm.SummaryTotal(tags[i].From, tags[i].To, opts...)
+		if err != nil {
+			return err
+		}
+
+		if format != "text" {
+			rs = append(rs, newSummaryRecords(tags[i].From, tags[i].To, tags[i].Elapsed, descs[i].Elapsed, total)...)
+			continue
+		}
+
+		label := tags[i].From.Format("2006-01-02 15:04") + " - " + tags[i].To.Format("2006-01-02 15:04")
+		fmt.Printf("Summary of %s\n%s\nTotal\t%s\n\n", label, newTagSummaries(tags[i].Elapsed, descs[i].Elapsed),
+			elapsedString(total.Elapsed, total.InProgress))
+	}
+
+	if format != "text" {
+		return writeRecords(c.App.Writer, format, summaryHeader, rs)
+	}
+
+	return nil
+}
+
+func parseOverlapPolicy(s string) (kokizami.OverlapPolicy, error) {
+	switch s {
+	case "warn":
+		return kokizami.OverlapWarn, nil
+	case "reject":
+		return kokizami.OverlapReject, nil
+	case "trim":
+		return kokizami.OverlapTrim, nil
+	}
+	return 0, fmt.Errorf("invalid overlap policy [%s]. should be one of warn, reject or trim", s)
+}
+
+func warnOverlap(k *kokizami.Kizami, overlapped []*kokizami.Kizami) {
+	for _, v := range overlapped {
+		fmt.Fprintf(os.Stderr, "warning: overlaps with %s\n", toString(v))
+	}
+}
+
+// CmdCheck reports conflicts of tasks
+func CmdCheck(c *cli.Context) error {
+	cs, err := kkzm(c).Check()
+	if err != nil {
+		return err
+	}
+
+	if len(cs) == 0 {
+		fmt.Println("no conflict found")
+		return nil
+	}
+
+	for _, v := range cs {
+		if v.B == nil {
+			fmt.Printf("stopped before started:\n  %s\n", toString(v.A))
+			continue
+		}
+		fmt.Printf("overlapped:\n  %s\n  %s\n", toString(v.A), toString(v.B))
+	}
+
+	return fmt.Errorf("%d conflict(s) found", len(cs))
+}
+
 // CmdTags shows list of tags
 func CmdTags(c *cli.Context) error {
-	var (
-		ts  []*kokizami.Tag
-		err error
-	)
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	var ts []*kokizami.Tag
 
 	kkzm := kkzm(c)
 	id := c.Int("id")
@@ -574,6 +1336,18 @@ func CmdTags(c *cli.Context) error {
 		}
 	}
 
+	if c.Bool("count") || c.Bool("elapsed") {
+		return tagUsages(c, kkzm, format, ts)
+	}
+
+	if format != "text" {
+		rs := make([]record, len(ts))
+		for i, v := range ts {
+			rs[i] = &tagRecord{ID: v.ID, Label: v.Label}
+		}
+		return writeRecords(c.App.Writer, format, tagHeader, rs)
+	}
+
 	buf := bytes.NewBuffer([]byte{})
 	for _, v := range ts {
 		fmt.Fprintln(buf, v.Label)
@@ -583,13 +1357,148 @@ func CmdTags(c *cli.Context) error {
 	return nil
 }
 
-func extractTagsFromString(s string) []string {
-	ss := strings.Split(s, " ")
-	var tags []string
-	for _, v := range ss {
-		if strings.HasPrefix(v, "#") && len(v) >= 2 {
-			tags = append(tags, v)
+// tagUsages shows number of tasks and total elapsed time of specified tags
+func tagUsages(c *cli.Context, kkzm *kokizami.Kokizami, format string, ts []*kokizami.Tag) error {
+	us, err := kkzm.TagUsages()
+	if err != nil {
+		return err
+	}
+	m := map[int]*kokizami.TagUsage{}
+	for _, v := range us {
+		m[v.Tag.ID] = v
+	}
+
+	if format != "text" {
+		rs := make([]record, len(ts))
+		for i, v := range ts {
+			rs[i] = newTagUsageRecord(m[v.ID])
+		}
+		return writeRecords(c.App.Writer, format, tagUsageHeader, rs)
+	}
+
+	for _, v := range ts {
+		u := m[v.ID]
+		cols := []string{v.Label}
+		if c.Bool("count") {
+			cols = append(cols, strconv.Itoa(u.Count))
+		}
+		if c.Bool("elapsed") {
+			cols = append(cols, round(u.Elapsed, time.Second).String())
 		}
+		fmt.Fprintln(c.App.Writer, strings.Join(cols, "\t"))
 	}
-	return tags
+	return nil
+}
+
+// tagLabel returns label of tag specified by user. "#" is prepended if missing.
+func tagLabel(s string) (string, error) {
+	if !strings.HasPrefix(s, "#") {
+		s = "#" + s
+	}
+	if len(s) < 2 {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	return s, nil
+}
+
+func tagLabels(ss []string) ([]string, error) {
+	ret := make([]string, len(ss))
+	for i, v := range ss {
+		l, err := tagLabel(v)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = l
+	}
+	return ret, nil
+}
+
+// CmdTagRename renames a tag
+// kokizami tag rename [tag] [new tag]
+func CmdTagRename(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return fmt.Errorf("specify a tag and a new tag")
+	}
+	ls, err := tagLabels(c.Args())
+	if err != nil {
+		return err
+	}
+
+	return kkzm(c).RenameTag(ls[0], ls[1])
+}
+
+// CmdTagMerge merges tags into a tag
+// kokizami tag merge [tags...] --into [tag]
+func CmdTagMerge(c *cli.Context) error {
+	if len(c.Args()) == 0 || c.String("into") == "" {
+		return fmt.Errorf("specify tags to merge and a tag by --into")
+	}
+	ls, err := tagLabels(c.Args())
+	if err != nil {
+		return err
+	}
+	into, err := tagLabel(c.String("into"))
+	if err != nil {
+		return err
+	}
+
+	return kkzm(c).MergeTags(ls, into)
+}
+
+// CmdTagRemove removes a tag
+// kokizami tag rm [tag]
+func CmdTagRemove(c *cli.Context) error {
+	if len(c.Args()) != 1 {
+		return fmt.Errorf("specify a tag to remove")
+	}
+	l, err := tagLabel(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	return kkzm(c).RemoveTag(l)
+}
+
+// CmdDBMigrate applies pending schema migrations
+func CmdDBMigrate(c *cli.Context) error {
+	db := kkzm(c).DB
+
+	if c.Bool("status") {
+		ss, err := models.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, v := range ss {
+			appliedAt := "pending"
+			if v.Applied {
+				appliedAt = v.AppliedAt.In(time.Local).Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\n", v.Version, appliedAt, v.Description)
+		}
+		return nil
+	}
+
+	ms, err := models.Migrate(db)
+	for _, v := range ms {
+		fmt.Printf("applied %d: %s\n", v.Version, v.Description)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ms) == 0 {
+		fmt.Println("database is up to date")
+	}
+	return nil
+}
+
+// CmdDBGC removes orphaned relations and intervals
+func CmdDBGC(c *cli.Context) error {
+	o, err := models.DeleteOrphans(kkzm(c).DB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("removed %d relation(s) and %d interval(s)\n", o.Relations, o.Intervals)
+	return nil
 }
@@ -1,6 +1,1058 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
+	"github.com/pankona/kokizami/sqlite"
+	"github.com/urfave/cli"
+)
+
+func setupApp(t *testing.T) (*cli.App, *kokizami.Kokizami, func()) {
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	kkzm, err := sqlite.Open(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	app := cli.NewApp()
+	app.Flags = globalFlags()
+	app.Commands = commands()
+	app.Metadata = map[string]interface{}{
+		"kkzm":   kkzm,
+		"config": filepath.Join(dir, "config.json"),
+	}
+
+	return app, kkzm, func() {
+		_ = kkzm.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
 
 func TestCmdAdd(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+
+	err := app.Run([]string{Name, "add", "meeting #work", "--from", "2019-05-01 09:30", "--to", "2019-05-01 10:15"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = app.Run([]string{Name, "add", "inverted", "--from", "10:15", "--to", "09:30"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	ks, err := kkzm.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ks), 1)
+	}
+	if ks[0].Elapsed() != 45*time.Minute {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ks[0].Elapsed(), 45*time.Minute)
+	}
+
+	ts, err := kkzm.TagsByKizamiID(ks[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 1 || ts[0].Label != "#work" {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ts, "#work")
+	}
+}
+
+func TestCmdDBMigrate(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+
+	db := kkzm.DB
+
+	ss, err := models.MigrationStatuses(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ss) != len(models.Migrations) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ss), len(models.Migrations))
+	}
+	for _, v := range ss {
+		if !v.Applied {
+			t.Errorf("unexpected result: migration %d is not applied", v.Version)
+		}
+	}
+
+	// migrating again should be no-op
+	ms, err := models.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] 0", len(ms))
+	}
+
+	err = app.Run([]string{Name, "db", "migrate", "--status"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = app.Run([]string{Name, "db", "migrate"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+}
+
+func TestMigrateLegacyDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	kkzm, err := sqlite.Open(filepath.Join(dir, "db"), sqlite.SkipMigration())
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = kkzm.Close() }()
+
+	db := kkzm.DB
+
+	// database created before migration is introduced
+	for _, m := range models.Migrations[:2] {
+		if err := m.Up(db); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	sqlstrs := []string{
+		`INSERT INTO kizami (desc) VALUES ('hoge #a')`,
+		`INSERT INTO tag (label) VALUES ('#a')`,
+		`INSERT INTO relation (kizami_id, tag_id) VALUES (1, 1), (2, 1), (1, 2)`,
+		`INSERT INTO interval (kizami_id) VALUES (1), (2)`,
+	}
+	for _, sqlstr := range sqlstrs {
+		if _, err := db.Exec(sqlstr); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	ms, err := models.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ms) != len(models.Migrations) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ms), len(models.Migrations))
+	}
+
+	count := func(table string) int {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		return n
+	}
+
+	// orphans are removed by migration
+	if n := count("relation"); n != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", n, 1)
+	}
+	if n := count("interval"); n != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", n, 1)
+	}
+
+	// relations and intervals are deleted with kizami
+	if _, err := db.Exec(`DELETE FROM kizami WHERE id = 1`); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if n := count("relation"); n != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", n, 0)
+	}
+	if n := count("interval"); n != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", n, 0)
+	}
+}
+
+func TestDeleteTagCascade(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+
+	k, err := kkzm.Start("hoge #a #b")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ts, err := kkzm.TagsByLabels([]string{"#a"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = kkzm.DeleteTag(ts[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ts, err = kkzm.TagsByKizamiID(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 1 || ts[0].Label != "#b" {
+		t.Fatalf("unexpected result: [got] %v [want] [#b]", ts)
+	}
+
+	db := kkzm.DB
+	o, err := models.DeleteOrphans(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if o.Relations != 0 || o.Intervals != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] no orphans", o)
+	}
+
+	err = app.Run([]string{Name, "db", "gc"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+}
+
+func TestNewTagSummaries(t *testing.T) {
+	// desc whose tag is not summarized is shown under the tag without elapsed time
+	tags := []*kokizami.Elapsed{{Tag: "#a", Elapsed: time.Hour}}
+	descs := []*kokizami.Elapsed{
+		{Tag: "#a", Desc: "hoge #a", Elapsed: time.Hour},
+		{Tag: "#b", Desc: "fuga #b", Elapsed: time.Hour},
+	}
+
+	got := newTagSummaries(tags, descs).String()
+	want := "#a\t1h0m0s\n  hoge #a\t1h0m0s\n#b\t0s\n  fuga #b\t1h0m0s\n"
+	if got != want {
+		t.Fatalf("unexpected result: [got] %q [want] %q", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	app, _, teardown := setupApp(t)
+	defer teardown()
+
+	err := app.Run([]string{Name, "add", "meeting, weekly #work #mtg", "--from", "2019-05-01 09:30", "--to", "2019-05-01 10:15"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	tcs := []struct {
+		inArgs []string
+		check  func(out string) error
+	}{
+		{
+			inArgs: []string{"list", "--format", "json"},
+			check: func(out string) error {
+				var rs []map[string]interface{}
+				if err := json.Unmarshal([]byte(out), &rs); err != nil {
+					return err
+				}
+				want := []map[string]interface{}{{
+					"id":              1.0,
+					"desc":            "meeting, weekly #work #mtg",
+					"started_at":      time.Date(2019, 5, 1, 9, 30, 0, 0, time.Local).Format(time.RFC3339),
+					"stopped_at":      time.Date(2019, 5, 1, 10, 15, 0, 0, time.Local).Format(time.RFC3339),
+					"elapsed_seconds": 2700.0,
+					"running":         false,
+					"paused":          false,
+					"tags":            []interface{}{"#mtg", "#work"},
+				}}
+				if diff := cmp.Diff(rs, want, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+					return fmt.Errorf("(-got +want) %s", diff)
+				}
+				return nil
+			},
+		},
+		{
+			inArgs: []string{"list", "--format", "csv"},
+			check: func(out string) error {
+				rs, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+				if err != nil {
+					return err
+				}
+				if len(rs) != 2 || rs[0][1] != "desc" || rs[1][1] != "meeting, weekly #work #mtg" || rs[1][4] != "2700" {
+					return fmt.Errorf("unexpected csv: %v", rs)
+				}
+				return nil
+			},
+		},
+		{
+			inArgs: []string{"summary", "--from", "2019-05-01", "--to", "2019-05-01", "--format", "ndjson"},
+			check: func(out string) error {
+				lines := strings.Split(strings.TrimSpace(out), "\n")
+				// 2 tags, 1 desc and total
+				if len(lines) != 4 {
+					return fmt.Errorf("unexpected ndjson: %v", lines)
+				}
+				var r map[string]interface{}
+				if err := json.Unmarshal([]byte(lines[2]), &r); err != nil {
+					return err
+				}
+				if r["kind"] != "desc" || r["tag"] != "#work" || r["elapsed_seconds"] != 2700.0 {
+					return fmt.Errorf("unexpected desc: %v", r)
+				}
+				if err := json.Unmarshal([]byte(lines[3]), &r); err != nil {
+					return err
+				}
+				if r["kind"] != "total" || r["elapsed_seconds"] != 2700.0 || r["count"] != 1.0 {
+					return fmt.Errorf("unexpected total: %v", r)
+				}
+				return nil
+			},
+		},
+		{
+			inArgs: []string{"tags", "--format", "tsv"},
+			check: func(out string) error {
+				if out != "id\tlabel\n1\t#work\n2\t#mtg\n" && out != "id\tlabel\n1\t#mtg\n2\t#work\n" {
+					return fmt.Errorf("unexpected tsv: %q", out)
+				}
+				return nil
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		buf.Reset()
+		err := app.Run(append([]string{Name}, tc.inArgs...))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if err := tc.check(buf.String()); err != nil {
+			t.Fatalf("[No.%d] unexpected result: %v", i, err)
+		}
+	}
+
+	err = app.Run([]string{Name, "list", "--format", "xml"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestCmdListTemplate(t *testing.T) {
+	app, _, teardown := setupApp(t)
+	defer teardown()
+
+	err := app.Run([]string{Name, "add", "meeting #work #mtg", "--from", "2019-05-01 09:30", "--to", "2019-05-01 11:00"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	err = app.Run([]string{Name, "template", "save", "short", `{{.ID}}:{{.Elapsed | hours}}`})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		inTemplate string
+		want       string
+	}{
+		{
+			inTemplate: `{{.ID}} {{.Desc}} {{.Elapsed | hours}}`,
+			want:       "1 meeting #work #mtg 1.50\n",
+		},
+		{
+			inTemplate: `{{date .StartedAt}} {{format "15:04" .StartedAt}}-{{clock .StoppedAt}} {{minutes .Elapsed}} {{.Running}}`,
+			want:       "2019-05-01 09:30-11:00:00 90 false\n",
+		},
+		{
+			inTemplate: `{{len .Tags}}{{"\n"}}`,
+			want:       "2\n",
+		},
+		{
+			inTemplate: "short",
+			want:       "1:1.50\n",
+		},
+	}
+
+	for i, tc := range tcs {
+		buf.Reset()
+		err := app.Run([]string{Name, "list", "--template", tc.inTemplate})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(buf.String(), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	err = app.Run([]string{Name, "list", "--template", "{{.Unknown}}"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	err = app.Run([]string{Name, "template", "rm", "short"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	buf.Reset()
+	err = app.Run([]string{Name, "template", "list"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected result: [got] %q [want] empty", buf.String())
+	}
+}
+
+func TestCmdListQuery(t *testing.T) {
+	app, _, teardown := setupApp(t)
+	defer teardown()
+
+	for _, v := range [][]string{
+		{"write #doc", "2019-05-01 09:00", "2019-05-01 10:00"},
+		{"review #code", "2019-05-01 10:00", "2019-05-01 10:30"},
+		{"write #code", "2019-05-02 09:00", "2019-05-02 12:00"},
+		{"lunch", "2019-05-02 12:00", "2019-05-02 13:00"},
+	} {
+		err := app.Run([]string{Name, "add", v[0], "--from", v[1], "--to", v[2]})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	tcs := []struct {
+		in   []string
+		want string
+	}{
+		{in: []string{"--since", "2019-05-02"}, want: "3 4"},
+		{in: []string{"--until", "2019-05-02"}, want: "1 2"},
+		{in: []string{"--tag", "code"}, want: "2 3"},
+		{in: []string{"--tag", "#code", "--tag", "!doc"}, want: "2 3"},
+		{in: []string{"--tag", "!code"}, want: "1 4"},
+		{in: []string{"--grep", "WRITE"}, want: "1 3"},
+		{in: []string{"--sort", "elapsed"}, want: "2 1 4 3"},
+		{in: []string{"--sort", "desc", "-r"}, want: "1 3 2 4"},
+		// the latest tasks are shown in ascending order
+		{in: []string{"-n", "2"}, want: "3 4"},
+		{in: []string{"-n", "2", "-r"}, want: "4 3"},
+		{in: []string{"-n", "2", "--offset", "1"}, want: "2 3"},
+		{in: []string{"--sort", "id", "-n", "2"}, want: "1 2"},
+		{in: []string{"--running"}, want: ""},
+	}
+
+	for i, tc := range tcs {
+		buf.Reset()
+		args := append([]string{Name, "list", "--template", `{{.ID}} `}, tc.in...)
+		err := app.Run(args)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		got := strings.Join(strings.Fields(buf.String()), " ")
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	for i, in := range [][]string{
+		{"--sort", "unknown"},
+		{"--since", "2019-05-02", "--until", "2019-05-01"},
+		{"-n=-1"},
+	} {
+		err := app.Run(append([]string{Name, "list"}, in...))
+		if err == nil {
+			t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+		}
+	}
+}
+
+func TestCmdSearch(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+
+	if kkzm.SearchRepo == nil {
+		t.Skip("FTS5 is not available. run tests with -tags sqlite_fts5")
+	}
+
+	for _, v := range []string{"migrate database #db", "lunch", "review migration plan"} {
+		err := app.Run([]string{Name, "add", v, "--from", "2019-05-01 09:30", "--to", "2019-05-01 10:00"})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	err := app.Run([]string{Name, "search", "lunch"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !strings.Contains(buf.String(), "[lunch]") {
+		t.Fatalf("unexpected result: [got] %s [want] a snippet of lunch", buf.String())
+	}
+
+	buf.Reset()
+	err = app.Run([]string{Name, "search", "--format", "csv", "migrat*"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Fatalf("unexpected result: [got] %d lines [want] 3 lines", n)
+	}
+
+	err = app.Run([]string{Name, "search"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestCmdTag(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+
+	for i, v := range []string{"write #doc", "review #dev #go", "fix #bug #go"} {
+		from := fmt.Sprintf("2019-05-01 %02d:00", 9+i)
+		to := fmt.Sprintf("2019-05-01 %02d:30", 9+i)
+		err := app.Run([]string{Name, "add", v, "--from", from, "--to", to})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	err := app.Run([]string{Name, "tags", "--count", "--elapsed"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(buf.String(), "#doc\t1\t30m0s\n#dev\t1\t30m0s\n#go\t2\t1h0m0s\n#bug\t1\t30m0s\n"); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	for _, args := range [][]string{
+		{"tag", "rename", "dev", "#code"},
+		{"tag", "merge", "#bug", "#go", "--into", "code"},
+		{"tag", "rm", "#doc"},
+	} {
+		err := app.Run(append([]string{Name}, args...))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	ks, err := kkzm.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := []string{}
+	for _, v := range ks {
+		got = append(got, v.Desc)
+	}
+	if diff := cmp.Diff(got, []string{"write doc", "review #code", "fix #code"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	buf.Reset()
+	err = app.Run([]string{Name, "tags", "--count"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(buf.String(), "#code\t2\n"); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	for _, args := range [][]string{
+		{"tag", "rename", "#unknown", "#code"},
+		{"tag", "rename", "#code"},
+		{"tag", "merge", "#code"},
+		{"tag", "rm"},
+	} {
+		err := app.Run(append([]string{Name}, args...))
+		if err == nil {
+			t.Fatalf("unexpected result: [got] nil [want] some error on %v", args)
+		}
+	}
+}
+
+func TestCmdHierarchicalTags(t *testing.T) {
+	app, _, teardown := setupApp(t)
+	defer teardown()
+
+	for i, v := range []string{"api #acme/backend", "ui #acme/frontend", "deploy #acme/backend/infra", "lunch"} {
+		from := fmt.Sprintf("2019-05-01 %02d:00", 9+i)
+		to := fmt.Sprintf("2019-05-01 %02d:30", 9+i)
+		err := app.Run([]string{Name, "add", v, "--from", from, "--to", to})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	tcs := []struct {
+		inDepth string
+		want    []string
+	}{
+		{inDepth: "1", want: []string{"#acme,3,5400", ",1,1800"}},
+		{inDepth: "2", want: []string{"#acme/backend,2,3600", "#acme/frontend,1,1800", ",1,1800"}},
+	}
+
+	for i, tc := range tcs {
+		buf.Reset()
+		err := app.Run([]string{Name, "summary", "--from", "2019-05-01", "--to", "2019-05-01", "--depth", tc.inDepth, "--format", "csv"})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		rs, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		got := []string{}
+		for _, r := range rs {
+			if r[2] == "tag" {
+				got = append(got, strings.Join([]string{r[3], r[5], r[6]}, ","))
+			}
+		}
+		if diff := cmp.Diff(got, tc.want, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	buf.Reset()
+	err := app.Run([]string{Name, "list", "--tag", "acme/backend", "--template", "{{.ID}} "})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(strings.Fields(buf.String()), []string{"1", "3"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = app.Run([]string{Name, "summary", "--depth=-1"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestSummaryRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	// 2019-06-01 05:00 in JST
+	now := time.Date(2019, 5, 31, 20, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		inArgs    []string
+		wantFrom  time.Time
+		wantLabel string
+	}{
+		{inArgs: []string{}, wantFrom: time.Date(2019, 6, 1, 0, 0, 0, 0, jst), wantLabel: "2019-06"},
+		{inArgs: []string{"--month", "2019-04"}, wantFrom: time.Date(2019, 4, 1, 0, 0, 0, 0, jst), wantLabel: "2019-04"},
+	}
+
+	for i, tc := range tcs {
+		set := flag.NewFlagSet("summary", flag.ContinueOnError)
+		set.String("month", "", "")
+		err := set.Parse(tc.inArgs)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		from, _, label, err := summaryRange(&kokizami.Kokizami{Location: jst}, cli.NewContext(nil, set, nil), now)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if !from.Equal(tc.wantFrom) || label != tc.wantLabel {
+			t.Fatalf("[No.%d] unexpected result: [got] %v %s [want] %v %s", i, from, label, tc.wantFrom, tc.wantLabel)
+		}
+	}
+}
+
+func TestCmdExportImport(t *testing.T) {
+	app, _, teardown := setupApp(t)
+	defer teardown()
+
+	for _, args := range [][]string{
+		{"add", "write #doc", "--from", "2019-05-01 09:00", "--to", "2019-05-01 10:00"},
+		{"add", "review #code", "--from", "2019-05-01 10:00", "--to", "2019-05-01 11:00"},
+	} {
+		err := app.Run(append([]string{Name}, args...))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "export.json")
+
+	err = app.Run([]string{Name, "export", "-o", path})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	app2, kkzm2, teardown2 := setupApp(t)
+	defer teardown2()
+	buf := bytes.NewBuffer([]byte{})
+	app2.Writer = buf
+
+	err = app2.Run([]string{Name, "add", "review #code", "--from", "2019-05-01 10:00", "--to", "2019-05-01 11:00"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		inMode    string
+		want      string
+		wantDescs []string
+	}{
+		{
+			inMode:    "merge",
+			want:      "imported 1 task(s), skipped 1 duplicate(s)\n",
+			wantDescs: []string{"review #code", "write #doc"},
+		},
+		{
+			inMode:    "replace",
+			want:      "imported 2 task(s), skipped 0 duplicate(s)\n",
+			wantDescs: []string{"write #doc", "review #code"},
+		},
+	}
+
+	for i, tc := range tcs {
+		buf.Reset()
+		err = app2.Run([]string{Name, "import", "--mode", tc.inMode, path})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(buf.String(), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+
+		ks, err := kkzm2.List()
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		got := []string{}
+		for _, v := range ks {
+			got = append(got, v.Desc)
+		}
+		if diff := cmp.Diff(got, tc.wantDescs); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// exported document is restored as it was
+	f, err := os.Open(path) // #nosec
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = f.Close() }()
+	want, err := kokizami.ReadExport(f)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got, err := kkzm2.Export()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(got.Kizamis, want.Kizamis, cmpopts.IgnoreFields(kokizami.ExportKizami{}, "ID")); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	for _, args := range [][]string{
+		{"import", "--mode", "unknown", path},
+		{"import", "--format", "csv", path},
+		{"import", filepath.Join(dir, "not-exist.json")},
+		{"export", "--format", "xml"},
+	} {
+		err := app2.Run(append([]string{Name}, args...))
+		if err == nil {
+			t.Fatalf("unexpected result: [got] nil [want] some error on %v", args)
+		}
+	}
+}
+
+func TestCmdTimewarrior(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timew")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	data := "inc 20190501T090000Z - 20190501T100000Z # code \"client acme\" # \"review\"\n" +
+		"inc 20190501T100000Z - 20190501T103000Z\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "2019-05.data"), []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	err = app.Run([]string{Name, "import", "--from", "timewarrior", dir})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ks, err := kkzm.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := []string{}
+	for _, v := range ks {
+		got = append(got, v.Desc)
+	}
+	if diff := cmp.Diff(got, []string{"review #code #client_acme", "(untitled)"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	buf.Reset()
+	err = app.Run([]string{Name, "export", "--to", "timewarrior"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := "inc 20190501T090000Z - 20190501T100000Z # client_acme code # \"review\"\n" +
+		"inc 20190501T100000Z - 20190501T103000Z # # \"(untitled)\"\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	out := filepath.Join(dir, "out")
+	err = app.Run([]string{Name, "export", "--to", "timewarrior", "-o", out})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(out, "2019-05.data")) // #nosec
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(string(b), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// existing data files are overwritten only with --force
+	err = app.Run([]string{Name, "export", "--to", "timewarrior", "-o", out})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	err = app.Run([]string{Name, "export", "--to", "timewarrior", "-o", out, "--force"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+}
+
+func TestCmdImportCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csv")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	data := "Entry,Day,From,To,Project\n" +
+		"write report,01.05.2019,09:00,10:30,Acme Corp\n" +
+		"invalid,2019-05-01,09:00,10:30,\n" +
+		"lunch,01.05.2019,12:00,,\n"
+	path := filepath.Join(dir, "entries.csv")
+	err = ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	err = app.Run([]string{Name, "import", "--from", "csv", "--preset", "toggl",
+		"--column", "desc=Entry", "--column", "start_date=Day", "--column", "start_time=From",
+		"--column", "end_date=", "--column", "end_time=To",
+		"--layout", "02.01.2006 15:04", "--source-tz", "Asia/Tokyo", path})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := "imported 1 task(s), skipped 0 duplicate(s)\n" +
+		"skipped 2 invalid row(s):\n" +
+		"  line 3: start: invalid time [2019-05-01 09:00]\n" +
+		"  line 4: end time or duration is empty\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ks, err := kkzm.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 1 {
+		t.Fatalf("unexpected result: [got] %d [want] 1", len(ks))
+	}
+	if ks[0].Desc != "write report #Acme_Corp" {
+		t.Fatalf("unexpected result: [got] %v [want] write report #Acme_Corp", ks[0].Desc)
+	}
+	if want := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC); !ks[0].StartedAt.Equal(want) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ks[0].StartedAt, want)
+	}
+
+	err = app.Run([]string{Name, "import", "--from", "csv", "--column", "unknown=Entry", path})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
+
+func TestCmdExportICS(t *testing.T) {
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	for _, v := range []struct {
+		desc  string
+		start time.Time
+	}{
+		{desc: "review #code", start: time.Date(2019, 4, 30, 9, 0, 0, 0, time.UTC)},
+		{desc: "meeting #team", start: time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)},
+	} {
+		_, err := kkzm.Add(v.desc, v.start, v.start.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	err := app.Run([]string{Name, "export", "--to", "ics",
+		"--since", "2019-05-01T00:00:00Z", "--until", "2019-05-02T00:00:00Z"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	got := strings.Split(buf.String(), "\r\n")
+	want := []string{
+		"BEGIN:VEVENT",
+		"UID:kizami-2@kokizami",
+	}
+	if !strings.Contains(buf.String(), strings.Join(want, "\r\n")) {
+		t.Fatalf("unexpected result: [got] %v [want] contains %v", got, want)
+	}
+	for _, v := range []string{"DTSTART:20190501T090000Z", "DTEND:20190501T100000Z", "SUMMARY:meeting #team", "CATEGORIES:team"} {
+		if !strings.Contains(buf.String(), v+"\r\n") {
+			t.Fatalf("unexpected result: [got] %v [want] contains %v", got, v)
+		}
+	}
+	if strings.Contains(buf.String(), "review") {
+		t.Fatalf("unexpected result: [got] %v [want] tasks only in range", got)
+	}
+
+	err = app.Run([]string{Name, "export", "--since", "yesterday"})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
+
+func TestCmdImportICS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"DTSTART:20190501T010000Z",
+		"DTEND:20190501T011500Z",
+		"SUMMARY:standup",
+		"CATEGORIES:team",
+		"RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:review@example.com",
+		"DTSTART:20190502T050000Z",
+		"DURATION:PT1H",
+		"SUMMARY:design review",
+		"CATEGORIES:client acme,review",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20190502T060000Z",
+		"DURATION:PT1H",
+		"SUMMARY:no uid",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	path := filepath.Join(dir, "calendar.ics")
+	err = ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	app, kkzm, teardown := setupApp(t)
+	defer teardown()
+	buf := bytes.NewBuffer([]byte{})
+	app.Writer = buf
+
+	args := []string{Name, "import", "--from", "ics", "--since", "2019-05-01T00:00:00Z", "--until", "2019-05-06T00:00:00Z", path}
+	err = app.Run(args)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := "imported 4 task(s), skipped 0 imported event(s)\n" +
+		"skipped 1 invalid event(s):\n" +
+		"   (no uid): UID is missing\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ks, err := kkzm.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := []string{}
+	for _, v := range ks {
+		got = append(got, v.StartedAt.UTC().Format("01-02T15:04")+" "+v.StoppedAt.UTC().Format("15:04")+" "+v.Desc)
+	}
+	wantKizamis := []string{
+		"05-01T01:00 01:15 standup #team",
+		"05-02T01:00 01:15 standup #team",
+		"05-02T05:00 06:00 design review #client_acme #review",
+		"05-03T01:00 01:15 standup #team",
+	}
+	if diff := cmp.Diff(got, wantKizamis); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// events imported already are skipped
+	buf.Reset()
+	err = app.Run(args)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !strings.HasPrefix(buf.String(), "imported 0 task(s), skipped 4 imported event(s)\n") {
+		t.Fatalf("unexpected result: [got] %v [want] all events skipped", buf.String())
+	}
+
+	err = app.Run([]string{Name, "import", "--since", "yesterday", path})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pankona/kokizami/sqlite"
+	"github.com/urfave/cli"
+)
+
+// config represents user configuration of kkzm
+// that is stored in config.json next to the database
+type config struct {
+	// Templates are named templates for list command
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// defaultConfigPath returns the path of config file
+func defaultConfigPath() (string, error) {
+	path, err := sqlite.DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "config.json"), nil
+}
+
+func configPath(c *cli.Context) string {
+	return c.App.Metadata["config"].(string)
+}
+
+// loadConfig loads config from specified path.
+// Empty config is returned if the file does not exist.
+func loadConfig(path string) (*config, error) {
+	conf := &config{Templates: map[string]string{}}
+
+	b, err := ioutil.ReadFile(path) // #nosec
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	err = json.Unmarshal(b, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	if conf.Templates == nil {
+		conf.Templates = map[string]string{}
+	}
+
+	return conf, nil
+}
+
+// saveConfig saves config to specified path
+func saveConfig(path string, conf *config) error {
+	b, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, append(b, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+	return nil
+}
+
+// CmdTemplateList shows named templates
+func CmdTemplateList(c *cli.Context) error {
+	conf, err := loadConfig(configPath(c))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(conf.Templates))
+	for k := range conf.Templates {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, v := range names {
+		fmt.Fprintf(c.App.Writer, "%s\t%s\n", v, conf.Templates[v])
+	}
+	return nil
+}
+
+// CmdTemplateSave saves a named template
+// kokizami template save [name] [template]
+func CmdTemplateSave(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 2 {
+		return fmt.Errorf("template save needs two arguments [name] [template]")
+	}
+
+	conf, err := loadConfig(configPath(c))
+	if err != nil {
+		return err
+	}
+
+	// validate template before saving
+	_, err = parseListTemplate(args[1], &config{})
+	if err != nil {
+		return err
+	}
+
+	conf.Templates[args[0]] = args[1]
+	return saveConfig(configPath(c), conf)
+}
+
+// CmdTemplateRemove removes a named template
+// kokizami template rm [name]
+func CmdTemplateRemove(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("template rm needs one argument [name]")
+	}
+
+	conf, err := loadConfig(configPath(c))
+	if err != nil {
+		return err
+	}
+
+	if _, ok := conf.Templates[args[0]]; !ok {
+		return fmt.Errorf("template [%s] is not found", args[0])
+	}
+	delete(conf.Templates, args[0])
+
+	return saveConfig(configPath(c), conf)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/csvimport"
+	"github.com/pankona/kokizami/ics"
+	"github.com/pankona/kokizami/timewarrior"
+	"github.com/urfave/cli"
+)
+
+// CmdExport exports all tasks and tags
+// kokizami export [--format json|timewarrior|ics] [-o file] [--force]
+func CmdExport(c *cli.Context) error {
+	format := c.String("format")
+	if format != "json" && format != "timewarrior" && format != "ics" {
+		return fmt.Errorf("unknown export format [%s]. one of json, timewarrior or ics is available", format)
+	}
+	if format != "ics" && (c.IsSet("since") || c.IsSet("until")) {
+		return fmt.Errorf("--since and --until are available only for ics format")
+	}
+	if format != "timewarrior" && c.Bool("force") {
+		return fmt.Errorf("--force is available only for timewarrior format")
+	}
+
+	path := c.String("output")
+	if format == "ics" {
+		return exportICS(c, path)
+	}
+
+	e, err := kkzm(c).Export()
+	if err != nil {
+		return err
+	}
+
+	if format == "timewarrior" {
+		ins := timewarrior.FromExport(e)
+		if path == "" {
+			return timewarrior.WriteIntervals(c.App.Writer, ins)
+		}
+		err = os.MkdirAll(path, 0755) // #nosec
+		if err != nil {
+			return fmt.Errorf("failed to create directory on %s: %v", path, err)
+		}
+		return timewarrior.Write(path, ins, c.Bool("force"))
+	}
+
+	return writeFile(c, path, func(w io.Writer) error {
+		return kokizami.WriteExport(w, e)
+	})
+}
+
+// timeRange returns times specified by --since and --until. Zero is returned for omitted ones.
+func timeRange(c *cli.Context, now time.Time) (since, until time.Time, err error) {
+	if c.IsSet("since") {
+		since, err = kokizami.ParseTime(c.String("since"), now)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if c.IsSet("until") {
+		until, err = kokizami.ParseTime(c.String("until"), now)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return since, until, nil
+}
+
+// exportICS writes tasks in range specified by --since and --until as iCalendar
+func exportICS(c *cli.Context, path string) error {
+	now := time.Now()
+	q := kokizami.KizamiQuery{Now: now}
+
+	var err error
+	q.Since, q.Until, err = timeRange(c, now)
+	if err != nil {
+		return err
+	}
+
+	ks, err := kkzm(c).Find(q)
+	if err != nil {
+		return err
+	}
+
+	es := ics.FromKizamis(ks, now)
+	return writeFile(c, path, func(w io.Writer) error {
+		return ics.Write(w, es)
+	})
+}
+
+// writeFile calls write with the file of path, or with standard output if path is empty
+func writeFile(c *cli.Context, path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(c.App.Writer)
+	}
+
+	f, err := os.Create(path) // #nosec
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	err = write(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// timewarriorDataDir returns the directory of Timewarrior data files
+func timewarriorDataDir() (string, error) {
+	dir := os.Getenv("TIMEWARRIORDB")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".timewarrior")
+	}
+	return filepath.Join(dir, "data"), nil
+}
+
+// openInput opens the file of path, or standard input if path is empty or "-"
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// readExport reads a document to import in specified format from path
+func readExport(format, path string) (*kokizami.Export, error) {
+	switch format {
+	case "json":
+		r, err := openInput(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = r.Close() }()
+		return kokizami.ReadExport(r)
+	case "timewarrior":
+		if path == "" {
+			dir, err := timewarriorDataDir()
+			if err != nil {
+				return nil, err
+			}
+			path = dir
+		}
+		ins, err := timewarrior.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		return timewarrior.ToExport(ins), nil
+	}
+	return nil, fmt.Errorf("unknown import format [%s]. one of json, timewarrior, csv or ics is available", format)
+}
+
+// readCSV reads time entries in CSV with mapping specified by flags.
+// Rows skipped as invalid are returned with their line numbers.
+func readCSV(c *cli.Context) (*csvimport.Result, error) {
+	m := csvimport.Mapping{}
+	if preset := c.String("preset"); preset != "" {
+		p, ok := csvimport.Presets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset [%s]. one of toggl or clockify is available", preset)
+		}
+		m = p
+	}
+	for _, v := range c.StringSlice("column") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid column [%s]. specify it as key=header", v)
+		}
+		err := m.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if layouts := c.StringSlice("layout"); len(layouts) != 0 {
+		m.Layouts = layouts
+	}
+
+	loc, err := sourceLocation(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := openInput(c.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return csvimport.Read(r, &m, loc)
+}
+
+// sourceLocation returns time zone specified by --source-tz, --tz or local time zone
+func sourceLocation(c *cli.Context) (*time.Location, error) {
+	if tz := c.String("source-tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone: %v", err)
+		}
+		return loc, nil
+	}
+	if k := kkzm(c); k.Location != nil {
+		return k.Location, nil
+	}
+	return time.Local, nil
+}
+
+// importICS imports events in a calendar started in range specified by --since and --until.
+// Events up to now are imported if --until is omitted.
+func importICS(c *cli.Context, mode kokizami.ImportMode) error {
+	now := time.Now()
+	since, until, err := timeRange(c, now)
+	if err != nil {
+		return err
+	}
+	if !c.IsSet("until") {
+		until = now
+	}
+
+	loc, err := sourceLocation(c)
+	if err != nil {
+		return err
+	}
+
+	r, err := openInput(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	es, err := ics.Read(r, loc)
+	if err != nil {
+		return err
+	}
+
+	ces, skipped := ics.ToCalendarEvents(es, since, until)
+	result, err := kkzm(c).ImportEvents(ces, mode)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "imported %d task(s), skipped %d imported event(s)\n", result.Imported, result.Skipped)
+	if len(skipped) != 0 {
+		fmt.Fprintf(c.App.Writer, "skipped %d invalid event(s):\n", len(skipped))
+		for _, v := range skipped {
+			fmt.Fprintf(c.App.Writer, "  %s (%s): %s\n", v.UID, v.Summary, v.Reason)
+		}
+	}
+	return nil
+}
+
+// CmdImport imports tasks and tags exported by export command or other tools
+// kokizami import [--format json|timewarrior|csv|ics] [file]
+func CmdImport(c *cli.Context) error {
+	var mode kokizami.ImportMode
+	switch c.String("mode") {
+	case "merge":
+		mode = kokizami.ImportMerge
+	case "replace":
+		mode = kokizami.ImportReplace
+	default:
+		return fmt.Errorf("unknown import mode [%s]. one of merge or replace is available", c.String("mode"))
+	}
+
+	if c.String("format") != "ics" && (c.IsSet("since") || c.IsSet("until")) {
+		return fmt.Errorf("--since and --until are available only for ics format")
+	}
+	if c.String("format") == "ics" {
+		return importICS(c, mode)
+	}
+
+	var e *kokizami.Export
+	var skipped []csvimport.Skipped
+	if c.String("format") == "csv" {
+		result, err := readCSV(c)
+		if err != nil {
+			return err
+		}
+		e, skipped = result.Export, result.Skipped
+	} else {
+		var err error
+		e, err = readExport(c.String("format"), c.Args().First())
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := kkzm(c).Import(e, mode)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "imported %d task(s), skipped %d duplicate(s)\n", result.Imported, result.Skipped)
+	if len(skipped) != 0 {
+		fmt.Fprintf(c.App.Writer, "skipped %d invalid row(s):\n", len(skipped))
+		for _, v := range skipped {
+			fmt.Fprintf(c.App.Writer, "  line %d: %s\n", v.Line, v.Reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// formatFlag is a flag to specify output format of read commands
+var formatFlag = cli.StringFlag{
+	Name:  "format",
+	Value: "text",
+	Usage: "specify output format. one of text, json, ndjson, csv or tsv",
+}
+
+// record is a row of machine-readable output
+type record interface {
+	// columns returns values in the order of header for csv and tsv
+	columns() []string
+}
+
+// outputFormat returns output format specified by format flag.
+// "text" is returned if the flag is not defined.
+func outputFormat(c *cli.Context) (string, error) {
+	format := c.String("format")
+	if format == "" {
+		format = "text"
+	}
+	if err := validateFormat(format); err != nil {
+		return "", err
+	}
+	return format, nil
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case "text", "json", "ndjson", "csv", "tsv":
+		return nil
+	}
+	return fmt.Errorf("invalid format [%s]. should be one of text, json, ndjson, csv or tsv", format)
+}
+
+// writeRecords writes records in specified machine-readable format
+func writeRecords(w io.Writer, format string, header []string, rs []record) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rs)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range rs {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, r := range rs {
+			if err := cw.Write(r.columns()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return validateFormat(format)
+}
+
+func timeString(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func secondsString(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
+
+// kizamiRecord represents a kizami in machine-readable output.
+// StoppedAt is nil if the kizami is on-going or paused.
+type kizamiRecord struct {
+	ID             int      `json:"id"`
+	Desc           string   `json:"desc"`
+	StartedAt      string   `json:"started_at"`
+	StoppedAt      *string  `json:"stopped_at"`
+	ElapsedSeconds float64  `json:"elapsed_seconds"`
+	Running        bool     `json:"running"`
+	Paused         bool     `json:"paused"`
+	Tags           []string `json:"tags"`
+}
+
+var kizamiHeader = []string{"id", "desc", "started_at", "stopped_at", "elapsed_seconds", "running", "paused", "tags"}
+
+func newKizamiRecord(k *kokizami.Kizami, ts []*kokizami.Tag) *kizamiRecord {
+	r := &kizamiRecord{
+		ID:             k.ID,
+		Desc:           k.Desc,
+		StartedAt:      timeString(k.StartedAt.In(time.Local)),
+		ElapsedSeconds: round(k.Elapsed(), time.Second).Seconds(),
+		Running:        k.StoppedAt.Unix() == 0 && !k.Paused(),
+		Paused:         k.Paused(),
+		Tags:           make([]string, len(ts)),
+	}
+	if k.StoppedAt.Unix() != 0 {
+		s := timeString(k.StoppedAt.In(time.Local))
+		r.StoppedAt = &s
+	}
+	for i := range ts {
+		r.Tags[i] = ts[i].Label
+	}
+	return r
+}
+
+func (r *kizamiRecord) columns() []string {
+	stoppedAt := ""
+	if r.StoppedAt != nil {
+		stoppedAt = *r.StoppedAt
+	}
+	return []string{
+		strconv.Itoa(r.ID),
+		r.Desc,
+		r.StartedAt,
+		stoppedAt,
+		secondsString(r.ElapsedSeconds),
+		strconv.FormatBool(r.Running),
+		strconv.FormatBool(r.Paused),
+		strings.Join(r.Tags, " "),
+	}
+}
+
+// summaryRecord represents a row of summary in machine-readable output.
+// Kind is one of "tag", "desc" or "total".
+type summaryRecord struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	Kind           string  `json:"kind"`
+	Tag            string  `json:"tag"`
+	Desc           string  `json:"desc"`
+	Count          int     `json:"count"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	InProgress     bool    `json:"in_progress"`
+}
+
+var summaryHeader = []string{"from", "to", "kind", "tag", "desc", "count", "elapsed_seconds", "in_progress"}
+
+// newSummaryRecords returns records of summary of the range [from, to)
+func newSummaryRecords(from, to time.Time, tags, descs []*kokizami.Elapsed, total *kokizami.Elapsed) []record {
+	rs := []record{}
+	add := func(kind string, e *kokizami.Elapsed) {
+		r := &summaryRecord{
+			From:           timeString(from),
+			To:             timeString(to),
+			Kind:           kind,
+			Tag:            e.Tag,
+			Count:          e.Count,
+			ElapsedSeconds: e.Elapsed.Seconds(),
+			InProgress:     e.InProgress,
+		}
+		if kind == "desc" {
+			r.Desc = e.Desc
+		}
+		rs = append(rs, r)
+	}
+
+	for _, v := range tags {
+		add("tag", v)
+	}
+	for _, v := range descs {
+		add("desc", v)
+	}
+	add("total", total)
+
+	return rs
+}
+
+func (r *summaryRecord) columns() []string {
+	return []string{
+		r.From,
+		r.To,
+		r.Kind,
+		r.Tag,
+		r.Desc,
+		strconv.Itoa(r.Count),
+		secondsString(r.ElapsedSeconds),
+		strconv.FormatBool(r.InProgress),
+	}
+}
+
+// tagRecord represents a tag in machine-readable output
+type tagRecord struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+var tagHeader = []string{"id", "label"}
+
+func (r *tagRecord) columns() []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		r.Label,
+	}
+}
+
+// tagUsageRecord represents usage of a tag in machine-readable output
+type tagUsageRecord struct {
+	ID             int     `json:"id"`
+	Label          string  `json:"label"`
+	Count          int     `json:"count"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+var tagUsageHeader = []string{"id", "label", "count", "elapsed_seconds"}
+
+func newTagUsageRecord(u *kokizami.TagUsage) *tagUsageRecord {
+	return &tagUsageRecord{
+		ID:             u.Tag.ID,
+		Label:          u.Tag.Label,
+		Count:          u.Count,
+		ElapsedSeconds: u.Elapsed.Seconds(),
+	}
+}
+
+func (r *tagUsageRecord) columns() []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		r.Label,
+		strconv.Itoa(r.Count),
+		secondsString(r.ElapsedSeconds),
+	}
+}
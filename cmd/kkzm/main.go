@@ -1,15 +1,13 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
-	"os/user"
-	"path/filepath"
+	"time"
 
 	"github.com/pankona/kokizami"
-	"github.com/pankona/kokizami/cmd/kkzm/repo"
 	"github.com/pankona/kokizami/models"
+	"github.com/pankona/kokizami/sqlite"
 	"github.com/urfave/cli"
 )
 
@@ -27,43 +25,55 @@ func main() {
 	app.Action = CmdList // show list if no argument
 	app.CommandNotFound = CommandNotFound
 
-	db := &sql.DB{}
-
 	app.Before = func(ctx *cli.Context) error {
-		u, err := user.Current()
+		path, err := sqlite.DefaultPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current user: %v", err)
+			return err
 		}
 
-		configDir := filepath.Join(u.HomeDir, ".config", "kokizami")
-		err = os.MkdirAll(configDir, 0755) // #nosec
+		policy, err := parseOverlapPolicy(ctx.String("overlap"))
 		if err != nil {
-			return fmt.Errorf("failed to create directory on %v", configDir)
+			return err
 		}
 
-		db, err = openDB(filepath.Join(configDir, "db"))
-		if err != nil {
-			return fmt.Errorf("failed to open DB: %v", err)
+		opts := []sqlite.OpenOption{
+			sqlite.WithOverlapPolicy(policy, warnOverlap),
 		}
 
-		err = repo.CreateTables(db)
-		if err != nil {
-			return fmt.Errorf("failed to create tables: %v", err)
+		if tz := ctx.String("tz"); tz != "" {
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return fmt.Errorf("failed to load time zone: %v", err)
+			}
+			opts = append(opts, sqlite.WithLocation(loc))
 		}
 
-		kkzm := &kokizami.Kokizami{
-			KizamiRepo:  repo.NewKizamiRepo(db),
-			TagRepo:     repo.NewTagRepo(db),
-			SummaryRepo: repo.NewSummaryRepo(db),
+		// leave pending migrations to "db migrate" so that it can report them
+		if isMigrateCommand(ctx.Args()) {
+			opts = append(opts, sqlite.SkipMigration())
+		}
+
+		kkzm, err := sqlite.Open(path, opts...)
+		if err != nil {
+			return err
 		}
 
 		app.Metadata["kkzm"] = kkzm
 
+		app.Metadata["config"], err = defaultConfigPath()
+		if err != nil {
+			return err
+		}
+
 		return nil
 	}
 
 	app.After = func(ctx *cli.Context) error {
-		return db.Close()
+		kkzm, ok := app.Metadata["kkzm"].(*kokizami.Kokizami)
+		if !ok {
+			return nil
+		}
+		return kkzm.Close()
 	}
 
 	err := app.Run(os.Args)
@@ -74,12 +84,8 @@ func main() {
 	os.Exit(0)
 }
 
-func openDB(dbPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, err
-	}
-	return db, nil
+func isMigrateCommand(args cli.Args) bool {
+	return len(args) >= 2 && args[0] == "db" && args[1] == "migrate"
 }
 
 func enableVerboseQuery(enable bool) {
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// listItem is the data model passed to templates of list command.
+//
+//	.ID        int            ID of the task
+//	.Desc      string         description of the task
+//	.StartedAt time.Time      started time in local time zone
+//	.StoppedAt time.Time      stopped time in local time zone. zero if running or paused
+//	.Elapsed   time.Duration  elapsed time except paused terms
+//	.Running   bool           true if the task is on-going
+//	.Paused    bool           true if the task is paused
+//	.Tags      []string       labels of tags of the task
+type listItem struct {
+	ID        int
+	Desc      string
+	StartedAt time.Time
+	StoppedAt time.Time
+	Elapsed   time.Duration
+	Running   bool
+	Paused    bool
+	Tags      []string
+}
+
+func newListItem(k *kokizami.Kizami, ts []*kokizami.Tag) *listItem {
+	item := &listItem{
+		ID:        k.ID,
+		Desc:      k.Desc,
+		StartedAt: k.StartedAt.In(time.Local),
+		Elapsed:   k.Elapsed(),
+		Running:   k.StoppedAt.Unix() == 0 && !k.Paused(),
+		Paused:    k.Paused(),
+		Tags:      make([]string, len(ts)),
+	}
+	if k.StoppedAt.Unix() != 0 {
+		item.StoppedAt = k.StoppedAt.In(time.Local)
+	}
+	for i := range ts {
+		item.Tags[i] = ts[i].Label
+	}
+	return item
+}
+
+// templateFuncs are helper functions available in templates
+//
+//	hours    duration -> hours in decimal (e.g. 1.50)
+//	minutes  duration -> minutes in integer
+//	seconds  duration -> seconds in integer
+//	round    duration -> duration rounded to seconds (e.g. 1h30m0s)
+//	date     time     -> "2006-01-02"
+//	clock    time     -> "15:04:05"
+//	datetime time     -> "2006-01-02 15:04:05"
+//	rfc3339  time     -> RFC3339
+//	format   layout time -> time formatted with the layout
+//	join     sep []string -> joined string
+//	upper, lower, pad (width string)
+var templateFuncs = template.FuncMap{
+	"hours": func(d time.Duration) string {
+		return fmt.Sprintf("%.2f", d.Hours())
+	},
+	"minutes": func(d time.Duration) int64 {
+		return int64(d / time.Minute)
+	},
+	"seconds": func(d time.Duration) int64 {
+		return int64(d / time.Second)
+	},
+	"round": func(d time.Duration) time.Duration {
+		return round(d, time.Second)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"clock": func(t time.Time) string {
+		return t.Format("15:04:05")
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"rfc3339": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+	"format": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"join": func(sep string, ss []string) string {
+		return strings.Join(ss, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"pad": func(width int, s string) string {
+		return fmt.Sprintf("%-*s", width, s)
+	},
+}
+
+// parseListTemplate parses specified template.
+// A named template in config is used if s is its name.
+func parseListTemplate(s string, conf *config) (*template.Template, error) {
+	if named, ok := conf.Templates[s]; ok {
+		s = named
+	}
+
+	t, err := template.New("list").Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+	return t, nil
+}
+
+// executeListTemplate writes each item with specified template.
+// A newline is appended to each item if the template does not end with it.
+func executeListTemplate(w io.Writer, t *template.Template, items []*listItem) error {
+	for _, v := range items {
+		buf := &strings.Builder{}
+		err := t.Execute(buf, v)
+		if err != nil {
+			return fmt.Errorf("failed to execute template: %v", err)
+		}
+		s := buf.String()
+		if !strings.HasSuffix(s, "\n") {
+			s += "\n"
+		}
+		if _, err := io.WriteString(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+// Package csvimport converts time entries in CSV exported by time trackers
+// such as Toggl and Clockify into kokizami.
+//
+// Columns of CSV are specified by Mapping, whose presets are available for
+// detailed reports of Toggl and Clockify. Tags and projects of entries are
+// mapped to #tags in desc of kizamis.
+package csvimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// Mapping specifies names of columns in the header of CSV.
+// Desc and StartDate are required. Either of end time or duration is required
+// for each entry. Optional columns that are not found in the header are ignored.
+type Mapping struct {
+	Desc string
+	// StartDate is the column of start date, or of start date and time if StartTime is empty
+	StartDate string
+	StartTime string
+	// EndDate is the column of end date. Start date is used if it is empty.
+	EndDate string
+	EndTime string
+	// Duration is used if end time of an entry is empty
+	Duration string
+	// Tags are columns whose values are comma separated tags, e.g. tags or project
+	Tags []string
+	// Layouts are layouts of time.Parse to parse date and time joined with a space.
+	// They are tried in order. DefaultLayouts are used if empty.
+	Layouts []string
+}
+
+// DefaultLayouts are layouts used if Layouts of Mapping is empty
+var DefaultLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// Toggl is the mapping of detailed report of Toggl Track
+var Toggl = Mapping{
+	Desc:      "Description",
+	StartDate: "Start date",
+	StartTime: "Start time",
+	EndDate:   "End date",
+	EndTime:   "End time",
+	Duration:  "Duration",
+	Tags:      []string{"Project", "Tags"},
+	Layouts: []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+	},
+}
+
+// Clockify is the mapping of detailed report of Clockify.
+// Both of 12-hour and 24-hour clock are accepted.
+var Clockify = Mapping{
+	Desc:      "Description",
+	StartDate: "Start Date",
+	StartTime: "Start Time",
+	EndDate:   "End Date",
+	EndTime:   "End Time",
+	Duration:  "Duration (h)",
+	Tags:      []string{"Project", "Tags"},
+	Layouts: []string{
+		"01/02/2006 03:04:05 PM",
+		"01/02/2006 03:04 PM",
+		"01/02/2006 15:04:05",
+		"01/02/2006 15:04",
+	},
+}
+
+// Presets are mappings available by name
+var Presets = map[string]Mapping{
+	"toggl":    Toggl,
+	"clockify": Clockify,
+}
+
+// Set sets a column of specified key, that is one of desc, start_date, start_time,
+// end_date, end_time, duration or tags. Columns of tags are separated by ",".
+func (m *Mapping) Set(key, column string) error {
+	switch key {
+	case "desc":
+		m.Desc = column
+	case "start_date":
+		m.StartDate = column
+	case "start_time":
+		m.StartTime = column
+	case "end_date":
+		m.EndDate = column
+	case "end_time":
+		m.EndTime = column
+	case "duration":
+		m.Duration = column
+	case "tags":
+		m.Tags = nil
+		for _, v := range strings.Split(column, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				m.Tags = append(m.Tags, v)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown column key [%s]. one of desc, start_date, start_time, end_date, end_time, duration or tags is available", key)
+	}
+	return nil
+}
+
+// Skipped represents an entry that is not imported because it is invalid
+type Skipped struct {
+	// Line is the line number where the entry starts. The header is line 1.
+	Line   int
+	Reason string
+}
+
+// Result represents result of reading CSV
+type Result struct {
+	Export  *kokizami.Export
+	Skipped []Skipped
+}
+
+// record is a record of CSV with the line number where it starts
+type record struct {
+	line   int
+	fields []string
+}
+
+// readRecords reads records of CSV with their line numbers.
+// Lines are joined until quotes are balanced since a quoted field may contain newlines.
+func readRecords(r io.Reader) ([]record, error) {
+	ret := []record{}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var chunk []string
+	start := 0
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if len(chunk) == 0 {
+			start = n
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+		}
+		chunk = append(chunk, line)
+
+		text := strings.Join(chunk, "\n")
+		if strings.Count(text, `"`)%2 != 0 {
+			continue
+		}
+		chunk = nil
+
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.FieldsPerRecord = -1
+		fields, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", start, err)
+		}
+		ret = append(ret, record{line: start, fields: fields})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(chunk) != 0 {
+		return nil, fmt.Errorf("line %d: unterminated quote", start)
+	}
+
+	return ret, nil
+}
+
+// columns resolves indexes of columns in the header
+type columns map[string]int
+
+// value returns trimmed value of a column in fields. Empty string is returned if the column is absent.
+func (cs columns) value(fields []string, column string) string {
+	i, ok := cs[strings.ToLower(column)]
+	if column == "" || !ok || i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// has returns true if the column is in the header
+func (cs columns) has(column string) bool {
+	_, ok := cs[strings.ToLower(column)]
+	return column != "" && ok
+}
+
+// parseTime parses date and time in loc with layouts
+func parseTime(date, clock string, layouts []string, loc *time.Location) (time.Time, error) {
+	s := strings.TrimSpace(date + " " + clock)
+	for _, l := range layouts {
+		t, err := time.ParseInLocation(l, s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time [%s]", s)
+}
+
+// parseDuration parses duration in hh:mm[:ss], decimal hours like 1.5 or Go's format like 1h30m
+func parseDuration(s string) (time.Duration, error) {
+	if strings.Contains(s, ":") {
+		ss := strings.Split(s, ":")
+		if len(ss) > 3 {
+			return 0, fmt.Errorf("invalid duration [%s]", s)
+		}
+		var d time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, v := range ss {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration [%s]", s)
+			}
+			d += time.Duration(n) * units[i]
+		}
+		return d, nil
+	}
+	if h, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(h * float64(time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration [%s]", s)
+	}
+	return d, nil
+}
+
+// Read reads time entries from CSV with mapping and converts them into kokizami's
+// Export document to import. Dates and times in CSV are interpreted in loc.
+// Invalid entries are skipped and reported with their line numbers.
+// Error is returned if CSV is malformed or required columns are not in the header.
+func Read(r io.Reader, m *Mapping, loc *time.Location) (*Result, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	layouts := m.Layouts
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+
+	rs, err := readRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("header is not found")
+	}
+
+	cs := columns{}
+	for i, v := range rs[0].fields {
+		cs[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+	for _, v := range []string{m.Desc, m.StartDate} {
+		if !cs.has(v) {
+			return nil, fmt.Errorf("column [%s] is not found in header", v)
+		}
+	}
+	if !cs.has(m.EndDate) && !cs.has(m.EndTime) && !cs.has(m.Duration) {
+		return nil, fmt.Errorf("column of end time or duration is not found in header")
+	}
+
+	ret := &Result{
+		Export: &kokizami.Export{
+			Version: kokizami.ExportVersion,
+			Kizamis: []kokizami.ExportKizami{},
+			Tags:    []string{},
+		},
+		Skipped: []Skipped{},
+	}
+	skip := func(line int, format string, a ...interface{}) {
+		ret.Skipped = append(ret.Skipped, Skipped{Line: line, Reason: fmt.Sprintf(format, a...)})
+	}
+
+	for _, rec := range rs[1:] {
+		f := rec.fields
+
+		date := cs.value(f, m.StartDate)
+		if date == "" {
+			skip(rec.line, "start date is empty")
+			continue
+		}
+		start, err := parseTime(date, cs.value(f, m.StartTime), layouts, loc)
+		if err != nil {
+			skip(rec.line, "start: %v", err)
+			continue
+		}
+
+		var end time.Time
+		endDate, endTime := cs.value(f, m.EndDate), cs.value(f, m.EndTime)
+		switch {
+		case endDate != "" || endTime != "":
+			if endDate == "" {
+				endDate = date
+			}
+			end, err = parseTime(endDate, endTime, layouts, loc)
+			if err != nil {
+				skip(rec.line, "end: %v", err)
+				continue
+			}
+			// entries over midnight without end date
+			if !cs.has(m.EndDate) && end.Before(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+		case cs.value(f, m.Duration) != "":
+			d, err := parseDuration(cs.value(f, m.Duration))
+			if err != nil {
+				skip(rec.line, "%v", err)
+				continue
+			}
+			end = start.Add(d)
+		default:
+			skip(rec.line, "end time or duration is empty")
+			continue
+		}
+		if !start.Before(end) {
+			skip(rec.line, "end time (%v) must be after start time (%v)", end, start)
+			continue
+		}
+
+		names := []string{}
+		for _, c := range m.Tags {
+			names = append(names, strings.Split(cs.value(f, c), ",")...)
+		}
+		desc := kokizami.ComposeDesc(cs.value(f, m.Desc), names)
+
+		stoppedAt := end.UTC()
+		ret.Export.Kizamis = append(ret.Export.Kizamis, kokizami.ExportKizami{
+			Desc:      desc,
+			StartedAt: start.UTC(),
+			StoppedAt: &stoppedAt,
+			Tags:      kokizami.ExtractTags(desc),
+		})
+	}
+
+	// reports of time trackers may be ordered from newer entries
+	ks := ret.Export.Kizamis
+	sort.SliceStable(ks, func(i, j int) bool {
+		return ks[i].StartedAt.Before(ks[j].StartedAt)
+	})
+	for i := range ks {
+		ks[i].ID = i + 1
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,167 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func at(d, h, m int) time.Time {
+	return time.Date(2019, 5, d, h, m, 0, 0, time.UTC)
+}
+
+func stopped(t time.Time) *time.Time {
+	return &t
+}
+
+func TestRead(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	custom := Mapping{
+		Desc:      "task",
+		StartDate: "from",
+		Duration:  "hours",
+		Tags:      []string{"labels"},
+	}
+
+	tcs := []struct {
+		inCSV       string
+		inMapping   Mapping
+		inLoc       *time.Location
+		want        []kokizami.ExportKizami
+		wantSkipped []Skipped
+	}{
+		{
+			inCSV: "\ufeffUser,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+				"me,me@example.com,,Acme Corp,,write report,No,2019-05-01,09:00:00,2019-05-01,10:30:00,01:30:00,\"docs, review\"\n" +
+				"me,me@example.com,,,,lunch,No,2019-05-01,12:00:00,2019-05-01,13:00:00,01:00:00,\n" +
+				"me,me@example.com,,Acme Corp,,,No,2019-05-01,23:00:00,2019-05-02,01:00:00,02:00:00,\n",
+			inMapping: Toggl,
+			inLoc:     time.UTC,
+			want: []kokizami.ExportKizami{
+				{
+					ID:        1,
+					Desc:      "write report #Acme_Corp #docs #review",
+					StartedAt: at(1, 9, 0),
+					StoppedAt: stopped(at(1, 10, 30)),
+					Tags:      []string{"#Acme_Corp", "#docs", "#review"},
+				},
+				{ID: 2, Desc: "lunch", StartedAt: at(1, 12, 0), StoppedAt: stopped(at(1, 13, 0))},
+				{
+					ID:        3,
+					Desc:      "#Acme_Corp",
+					StartedAt: at(1, 23, 0),
+					StoppedAt: stopped(at(2, 1, 0)),
+					Tags:      []string{"#Acme_Corp"},
+				},
+			},
+			wantSkipped: []Skipped{},
+		},
+		{
+			// newer entries first, times in source time zone, invalid rows
+			inCSV: "Project,Client,Description,Task,User,Group,Email,Tags,Billable,Start Date,Start Time,End Date,End Time,Duration (h)\n" +
+				",,\"multi\nline\",,me,,me@example.com,,No,05/01/2019,02:00:00 PM,05/01/2019,03:00:00 PM,01:00:00\n" +
+				",,broken,,me,,me@example.com,,No,2019-05-01,09:00,,,\n" +
+				",,no end,,me,,me@example.com,,No,05/01/2019,10:00,,,\n" +
+				",,reversed,,me,,me@example.com,,No,05/01/2019,11:00,05/01/2019,10:00,\n" +
+				",,meeting,,me,,me@example.com,#meeting,No,05/01/2019,09:00,05/01/2019,09:30,00:30:00\n",
+			inMapping: Clockify,
+			inLoc:     jst,
+			want: []kokizami.ExportKizami{
+				{
+					ID:        1,
+					Desc:      "meeting #meeting",
+					StartedAt: at(1, 0, 0),
+					StoppedAt: stopped(at(1, 0, 30)),
+					Tags:      []string{"#meeting"},
+				},
+				{ID: 2, Desc: "multi line", StartedAt: at(1, 5, 0), StoppedAt: stopped(at(1, 6, 0))},
+			},
+			wantSkipped: []Skipped{
+				{Line: 4, Reason: "start: invalid time [2019-05-01 09:00]"},
+				{Line: 5, Reason: "end time or duration is empty"},
+				{Line: 6, Reason: "end time (2019-05-01 10:00:00 +0900 JST) must be after start time (2019-05-01 11:00:00 +0900 JST)"},
+			},
+		},
+		{
+			// custom mapping with duration in decimal hours
+			inCSV: "task,from,hours,labels\n" +
+				"\n" +
+				"design,2019-05-01T09:00:00Z,1.5,ui\n" +
+				"deploy,2019-05-01 13:00,,\n" +
+				"code,2019-05-01 10:30,1h,\n",
+			inMapping: custom,
+			inLoc:     time.UTC,
+			want: []kokizami.ExportKizami{
+				{
+					ID:        1,
+					Desc:      "design #ui",
+					StartedAt: at(1, 9, 0),
+					StoppedAt: stopped(at(1, 10, 30)),
+					Tags:      []string{"#ui"},
+				},
+				{ID: 2, Desc: "code", StartedAt: at(1, 10, 30), StoppedAt: stopped(at(1, 11, 30))},
+			},
+			wantSkipped: []Skipped{
+				{Line: 4, Reason: "end time or duration is empty"},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		got, err := Read(strings.NewReader(tc.inCSV), &tc.inMapping, tc.inLoc)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got.Export.Kizamis, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected kizamis (-got +want)\n%s", i, diff)
+		}
+		if diff := cmp.Diff(got.Skipped, tc.wantSkipped); diff != "" {
+			t.Errorf("[No.%d] unexpected skipped (-got +want)\n%s", i, diff)
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tcs := []struct {
+		inCSV string
+	}{
+		{inCSV: ""},
+		{inCSV: "Start date,Start time,End date,End time\n"},
+		{inCSV: "Description,Start date,Start time\n"},
+		{inCSV: "Description,Start date,Start time,End date,End time\n\"unterminated,2019-05-01\n"},
+	}
+
+	for i, tc := range tcs {
+		_, err := Read(strings.NewReader(tc.inCSV), &Toggl, time.UTC)
+		if err == nil {
+			t.Fatalf("[No.%d] unexpected result: [got] nil [want] error", i)
+		}
+	}
+}
+
+func TestMappingSet(t *testing.T) {
+	m := Toggl
+	for _, v := range [][2]string{{"desc", "Task"}, {"tags", "Client, Project"}, {"duration", ""}} {
+		err := m.Set(v[0], v[1])
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	if m.Desc != "Task" || m.Duration != "" {
+		t.Errorf("unexpected result: [got] %v", m)
+	}
+	if diff := cmp.Diff(m.Tags, []string{"Client", "Project"}); diff != "" {
+		t.Errorf("unexpected tags (-got +want)\n%s", diff)
+	}
+	if diff := cmp.Diff(Toggl.Tags, []string{"Project", "Tags"}); diff != "" {
+		t.Errorf("preset must not be modified (-got +want)\n%s", diff)
+	}
+
+	if err := m.Set("unknown", "x"); err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
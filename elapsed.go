@@ -10,10 +10,115 @@ type Elapsed struct {
 	Desc    string
 	Count   int
 	Elapsed time.Duration
+	// InProgress is true if on-going kizamis are counted in Elapsed
+	InProgress bool
 }
 
-// SummaryRepository is an interface to fetch summaries from repository
+// PeriodSummary represents elapsed times of Kizamis in a period
+type PeriodSummary struct {
+	From    time.Time
+	To      time.Time
+	Elapsed []*Elapsed
+}
+
+// SummaryRepository is an interface to fetch summaries from repository.
+// Each method summarizes kizamis in the half-open range [from, to).
+// Kizamis that straddle the range boundaries are clipped to the range.
+// ElapsedByDesc counts each kizami once regardless of Attribution with its primary tag as Tag.
 type SummaryRepository interface {
-	ElapsedOfMonthByDesc(yyyymm string) ([]*Elapsed, error)
-	ElapsedOfMonthByTag(yyyymm string) ([]*Elapsed, error)
+	ElapsedByDesc(from, to time.Time, opts *SummaryOptions) ([]*Elapsed, error)
+	ElapsedByTag(from, to time.Time, opts *SummaryOptions) ([]*Elapsed, error)
+	ElapsedTotal(from, to time.Time, opts *SummaryOptions) (*Elapsed, error)
+}
+
+// Attribution represents how to attribute elapsed time of a kizami to its tags
+type Attribution int
+
+const (
+	// AttributionFull attributes full elapsed time of a kizami to each of its tags.
+	// Sum of elapsed time of tags may exceed the total if kizamis have multiple tags.
+	AttributionFull Attribution = iota
+	// AttributionSplit splits elapsed time of a kizami evenly among its tags
+	AttributionSplit
+	// AttributionPrimary attributes elapsed time of a kizami only to its primary tag,
+	// that appears first in its desc
+	AttributionPrimary
+)
+
+// SummaryOptions represents options of summaries
+type SummaryOptions struct {
+	// IncludeRunning specifies to count on-going kizamis up to Now
+	IncludeRunning bool
+	// Now is used as stopped_at of on-going kizamis
+	Now time.Time
+	// Attribution specifies how to attribute elapsed time to tags
+	Attribution Attribution
+	// Depth specifies to roll hierarchical tags up to their ancestors at the depth.
+	// Tags are not rolled up if 0.
+	Depth int
+}
+
+// SummaryOption is a function to modify SummaryOptions
+type SummaryOption func(*SummaryOptions)
+
+// IncludeRunning returns a SummaryOption to count on-going kizamis
+// up to current time
+func IncludeRunning() SummaryOption {
+	return func(o *SummaryOptions) {
+		o.IncludeRunning = true
+	}
+}
+
+// WithDepth returns a SummaryOption to roll hierarchical tags up to
+// their ancestors at specified depth, e.g. #a/b/c is summarized as #a/b at depth 2.
+// Each kizami is counted once for an ancestor even if it has several tags under it.
+func WithDepth(depth int) SummaryOption {
+	return func(o *SummaryOptions) {
+		o.Depth = depth
+	}
+}
+
+// WithAttribution returns a SummaryOption to specify
+// how to attribute elapsed time to tags
+func WithAttribution(a Attribution) SummaryOption {
+	return func(o *SummaryOptions) {
+		o.Attribution = a
+	}
+}
+
+// Period represents a granularity of summaries
+type Period int
+
+const (
+	// PeriodDay represents a day
+	PeriodDay Period = iota + 1
+	// PeriodWeek represents a week that starts on Monday
+	PeriodWeek
+	// PeriodMonth represents a month
+	PeriodMonth
+)
+
+// Range returns the half-open range [from, to) of the period that contains t.
+// The range is calculated in t's location.
+func (p Period) Range(t time.Time) (from, to time.Time) {
+	y, m, d := t.Date()
+	loc := t.Location()
+
+	switch p {
+	case PeriodDay:
+		from = time.Date(y, m, d, 0, 0, 0, 0, loc)
+		to = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	case PeriodWeek:
+		// time.Weekday starts on Sunday. shift it to start on Monday.
+		offset := (int(t.Weekday()) + 6) % 7
+		from = time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+		to = time.Date(y, m, d-offset+7, 0, 0, 0, 0, loc)
+	case PeriodMonth:
+		from = time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		to = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+	default:
+		panic("unknown period")
+	}
+
+	return from, to
 }
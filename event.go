@@ -0,0 +1,134 @@
+package kokizami
+
+import (
+	"fmt"
+	"time"
+)
+
+// CalendarEvent represents an occurrence of an event in calendars to import as a kizami
+type CalendarEvent struct {
+	UID string
+	// RecurrenceID identifies an occurrence of a recurring event.
+	// It is empty if the event is not recurring.
+	RecurrenceID string
+	// KizamiID is ID of the kizami that the event is exported from, or 0 if it is not.
+	KizamiID  int
+	Desc      string
+	StartedAt time.Time
+	StoppedAt time.Time
+}
+
+// EventRepository is an interface to track calendar events imported as kizamis.
+// Tracks of an event are removed when the kizami imported from it is deleted.
+type EventRepository interface {
+	// FindKizamiID returns ID of the kizami imported from specified event.
+	// 0 is returned if the event has not been imported.
+	FindKizamiID(uid, recurrenceID string) (int, error)
+	Insert(uid, recurrenceID string, kizamiID int) error
+}
+
+// validate returns error if the event can not be imported
+func (e *CalendarEvent) validate() error {
+	if e.UID == "" {
+		return fmt.Errorf("UID of event must not be empty")
+	}
+	if e.Desc == "" {
+		return fmt.Errorf("desc of event [%s] must not be empty", e.UID)
+	}
+	if !e.StartedAt.Before(e.StoppedAt) {
+		return fmt.Errorf("stopped time (%v) of event [%s] must be after started time (%v)", e.StoppedAt, e.UID, e.StartedAt)
+	}
+	return nil
+}
+
+// ImportEvents imports calendar events as stopped kizamis tagged with tags in their desc.
+// Events that have been imported already are skipped, so that the same calendar
+// can be imported repeatedly. Events exported from kizamis are skipped as well if the kizami
+// of KizamiID exists with the same started time in seconds, so that exported calendars
+// can be imported back. IDs of the result are keyed by index of events.
+// Events are imported regardless of OverlapPolicy.
+// Nothing is imported if any of events is invalid.
+func (k *Kokizami) ImportEvents(es []CalendarEvent, mode ImportMode) (*ImportResult, error) {
+	if k.EventRepo == nil {
+		return nil, fmt.Errorf("import of calendar events is not available")
+	}
+	for i := range es {
+		if err := es[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := &ImportResult{IDs: map[int]int{}}
+	err := k.Transaction(func(tx *Kokizami) error {
+		if mode == ImportReplace {
+			err := tx.deleteAll()
+			if err != nil {
+				return err
+			}
+		}
+
+		exported, err := tx.startedAtOfExported(es)
+		if err != nil {
+			return err
+		}
+
+		for i, v := range es {
+			if t, ok := exported[v.KizamiID]; ok && t == v.StartedAt.Unix() {
+				ret.Skipped++
+				continue
+			}
+
+			id, err := tx.EventRepo.FindKizamiID(v.UID, v.RecurrenceID)
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				ret.Skipped++
+				continue
+			}
+
+			ki, err := tx.KizamiRepo.InsertWithTime(v.Desc, v.StartedAt.UTC(), v.StoppedAt.UTC())
+			if err != nil {
+				return err
+			}
+			err = tx.retag(ki.ID, v.Desc)
+			if err != nil {
+				return err
+			}
+			err = tx.EventRepo.Insert(v.UID, v.RecurrenceID, ki.ID)
+			if err != nil {
+				return err
+			}
+			ret.IDs[i] = ki.ID
+			ret.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// startedAtOfExported returns started time in seconds of existing kizamis keyed by their ID,
+// if any of events is exported from a kizami
+func (k *Kokizami) startedAtOfExported(es []CalendarEvent) (map[int]int64, error) {
+	ret := map[int]int64{}
+	found := false
+	for _, v := range es {
+		found = found || v.KizamiID != 0
+	}
+	if !found {
+		return ret, nil
+	}
+
+	ks, err := k.KizamiRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range ks {
+		ret[v.ID] = v.StartedAt.Unix()
+	}
+	return ret, nil
+}
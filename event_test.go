@@ -0,0 +1,83 @@
+package kokizami_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func TestImportEvents(t *testing.T) {
+	base := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+	es := []kokizami.CalendarEvent{
+		{UID: "a@example.com", Desc: "planning #team", StartedAt: base, StoppedAt: base.Add(time.Hour)},
+		{UID: "b@example.com", RecurrenceID: "20190501T120000Z", Desc: "sync #team", StartedAt: base.Add(3 * time.Hour), StoppedAt: base.Add(4 * time.Hour)},
+		{UID: "b@example.com", RecurrenceID: "20190502T120000Z", Desc: "sync #team", StartedAt: base.Add(27 * time.Hour), StoppedAt: base.Add(28 * time.Hour)},
+	}
+
+	k := newMemKokizami()
+	result, err := k.ImportEvents(es, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if result.Imported != 3 || result.Skipped != 0 {
+		t.Fatalf("unexpected result: [got] %+v [want] 3 imported", result)
+	}
+	if diff := cmp.Diff(descs(t, k), []string{"planning #team", "sync #team", "sync #team"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	if diff := cmp.Diff(tagLabels(t, k), []string{"#team"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	// kizamis are tagged as ones started by desc
+	for i, id := range result.IDs {
+		ts, err := k.TagsByKizamiID(id)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if len(ts) != 1 || ts[0].Label != "#team" {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] [#team]", i, ts)
+		}
+	}
+
+	// imported events are skipped
+	result, err = k.ImportEvents(es, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if result.Imported != 0 || result.Skipped != 3 {
+		t.Fatalf("unexpected result: [got] %+v [want] 3 skipped", result)
+	}
+
+	// deleted ones are imported again
+	ks, err := k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Delete(ks[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	result, err = k.ImportEvents(es, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if result.Imported != 1 || result.Skipped != 2 {
+		t.Fatalf("unexpected result: [got] %+v [want] 1 imported and 2 skipped", result)
+	}
+
+	// nothing is imported if any of events is invalid
+	invalid := append([]kokizami.CalendarEvent{}, es...)
+	invalid = append(invalid, kokizami.CalendarEvent{UID: "c@example.com", Desc: "empty", StartedAt: base, StoppedAt: base})
+	_, err = newMemKokizami().ImportEvents(invalid, kokizami.ImportMerge)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+
+	k.EventRepo = nil
+	_, err = k.ImportEvents(es, kokizami.ImportMerge)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
@@ -0,0 +1,302 @@
+package kokizami
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ExportVersion is the version of Export document.
+// Increment it on incompatible changes of the document.
+const ExportVersion = 1
+
+// Export represents a whole database as a versioned document.
+// It is encoded to and decoded from JSON to move kizamis between databases.
+type Export struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Kizamis    []ExportKizami `json:"kizamis"`
+	// Tags are labels of all tags including ones no kizami has
+	Tags []string `json:"tags"`
+}
+
+// ExportKizami represents a kizami in Export document.
+// ID is the ID in the exported database and is not kept on import.
+type ExportKizami struct {
+	ID        int       `json:"id"`
+	Desc      string    `json:"desc"`
+	StartedAt time.Time `json:"started_at"`
+	// StoppedAt is nil if the kizami is on-going
+	StoppedAt *time.Time `json:"stopped_at"`
+	// Intervals are terms that the kizami is actually on-going if it has been paused
+	Intervals []ExportInterval `json:"intervals,omitempty"`
+	// Tags are labels of tags of the kizami. They are informational on import
+	// since the kizami is tagged with tags contained in Desc as usual.
+	Tags []string `json:"tags"`
+}
+
+// ExportInterval represents an interval of a kizami in Export document
+type ExportInterval struct {
+	StartedAt time.Time `json:"started_at"`
+	// StoppedAt is nil if the interval is on-going
+	StoppedAt *time.Time `json:"stopped_at"`
+}
+
+// ImportMode specifies how to import kizamis into existing ones
+type ImportMode int
+
+const (
+	// ImportMerge adds kizamis to existing ones.
+	// Kizamis that have same desc and started time as existing ones are skipped.
+	ImportMerge ImportMode = iota
+	// ImportReplace deletes all existing kizamis and tags before import
+	ImportReplace
+)
+
+// ImportResult represents result of import
+type ImportResult struct {
+	Imported int
+	// Skipped is the number of kizamis skipped as duplicates
+	Skipped int
+	// IDs maps IDs in the document to IDs of imported kizamis
+	IDs map[int]int
+}
+
+// stoppedAtPtr returns nil if t is initial value that represents on-going
+func stoppedAtPtr(t time.Time) *time.Time {
+	if t.Unix() == 0 {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// stoppedAtOf returns initial value if t is nil
+func stoppedAtOf(t *time.Time) time.Time {
+	if t == nil {
+		return initialTime()
+	}
+	return t.UTC()
+}
+
+// Export returns all kizamis and tags as Export document
+func (k *Kokizami) Export() (*Export, error) {
+	ks, err := k.KizamiRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(ks))
+	for i, v := range ks {
+		ids[i] = v.ID
+	}
+	tags, err := k.TagsByKizamiIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Export{
+		Version:    ExportVersion,
+		ExportedAt: k.timeNow().UTC(),
+		Kizamis:    make([]ExportKizami, len(ks)),
+		Tags:       []string{},
+	}
+	for i, v := range ks {
+		ts := tags[v.ID]
+		ek := ExportKizami{
+			ID:        v.ID,
+			Desc:      v.Desc,
+			StartedAt: v.StartedAt.UTC(),
+			StoppedAt: stoppedAtPtr(v.StoppedAt),
+			Tags:      make([]string, len(ts)),
+		}
+		for j, t := range ts {
+			ek.Tags[j] = t.Label
+		}
+		for _, in := range v.Intervals {
+			ek.Intervals = append(ek.Intervals, ExportInterval{
+				StartedAt: in.StartedAt.UTC(),
+				StoppedAt: stoppedAtPtr(in.StoppedAt),
+			})
+		}
+		e.Kizamis[i] = ek
+	}
+
+	ts, err := k.Tags()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range ts {
+		e.Tags = append(e.Tags, v.Label)
+	}
+
+	return e, nil
+}
+
+// WriteExport writes Export document as indented JSON
+func WriteExport(w io.Writer, e *Export) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// ReadExport reads Export document from JSON.
+// Error is returned if the version of the document is not supported.
+func ReadExport(r io.Reader) (*Export, error) {
+	e := &Export{}
+	err := json.NewDecoder(r).Decode(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode export document: %v", err)
+	}
+	if e.Version < 1 || e.Version > ExportVersion {
+		return nil, fmt.Errorf("unsupported version of export document [%d]. %d or older is supported", e.Version, ExportVersion)
+	}
+	return e, nil
+}
+
+// validate returns error if the kizami can not be imported
+func (ek *ExportKizami) validate() error {
+	if ek.Desc == "" {
+		return fmt.Errorf("desc of kizami [%d] must not be empty", ek.ID)
+	}
+	if ek.StartedAt.IsZero() {
+		return fmt.Errorf("started_at of kizami [%d] must be specified", ek.ID)
+	}
+	if ek.StoppedAt != nil && !ek.StartedAt.Before(*ek.StoppedAt) {
+		return fmt.Errorf("stopped_at (%v) of kizami [%d] must be after started_at (%v)", *ek.StoppedAt, ek.ID, ek.StartedAt)
+	}
+	for _, in := range ek.Intervals {
+		if in.StoppedAt != nil && !in.StartedAt.Before(*in.StoppedAt) {
+			return fmt.Errorf("stopped_at (%v) of interval of kizami [%d] must be after started_at (%v)", *in.StoppedAt, ek.ID, in.StartedAt)
+		}
+	}
+	for _, v := range ek.Tags {
+		if err := validateLabel(v); err != nil {
+			return fmt.Errorf("kizami [%d] has %v", ek.ID, err)
+		}
+	}
+	return nil
+}
+
+// importKey identifies a kizami to detect duplicates on import
+type importKey struct {
+	desc      string
+	startedAt int64
+}
+
+// Import imports kizamis and tags in Export document.
+// New IDs are assigned to imported kizamis. Duplicates of existing kizamis
+// or of preceding ones in the document, that have same desc and started time
+// in seconds, are skipped. Kizamis are imported regardless of OverlapPolicy.
+// Nothing is imported if any of kizamis or tags is invalid.
+func (k *Kokizami) Import(e *Export, mode ImportMode) (*ImportResult, error) {
+	for i := range e.Kizamis {
+		if err := e.Kizamis[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range e.Tags {
+		if err := validateLabel(v); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := &ImportResult{IDs: map[int]int{}}
+	err := k.Transaction(func(tx *Kokizami) error {
+		if mode == ImportReplace {
+			err := tx.deleteAll()
+			if err != nil {
+				return err
+			}
+		}
+
+		ks, err := tx.KizamiRepo.FindAll()
+		if err != nil {
+			return err
+		}
+		seen := map[importKey]bool{}
+		for _, v := range ks {
+			seen[importKey{desc: v.Desc, startedAt: v.StartedAt.Unix()}] = true
+		}
+
+		err = tx.TagRepo.Insert(e.Tags)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range e.Kizamis {
+			key := importKey{desc: v.Desc, startedAt: v.StartedAt.Unix()}
+			if seen[key] {
+				ret.Skipped++
+				continue
+			}
+			seen[key] = true
+
+			id, err := tx.importKizami(&v)
+			if err != nil {
+				return err
+			}
+			ret.IDs[v.ID] = id
+			ret.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// importKizami inserts a kizami with its intervals, tags it with tags contained in its desc and returns its ID
+func (k *Kokizami) importKizami(ek *ExportKizami) (int, error) {
+	ki, err := k.KizamiRepo.InsertWithTime(ek.Desc, ek.StartedAt.UTC(), stoppedAtOf(ek.StoppedAt))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, in := range ek.Intervals {
+		err = k.KizamiRepo.InsertInterval(&Interval{
+			KizamiID:  ki.ID,
+			StartedAt: in.StartedAt.UTC(),
+			StoppedAt: stoppedAtOf(in.StoppedAt),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return ki.ID, k.retag(ki.ID, ek.Desc)
+}
+
+// deleteAll deletes all kizamis and tags
+func (k *Kokizami) deleteAll() error {
+	ks, err := k.KizamiRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, v := range ks {
+		err = k.KizamiRepo.Untagging(v.ID)
+		if err != nil {
+			return err
+		}
+		err = k.KizamiRepo.Delete(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	ts, err := k.TagRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, v := range ts {
+		err = k.TagRepo.Delete(v.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
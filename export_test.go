@@ -0,0 +1,176 @@
+package kokizami_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pankona/kokizami"
+)
+
+func TestExportImport(t *testing.T) {
+	base := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	src := newMemKokizami()
+	_, err := src.Add("write #doc", base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	paused, err := src.StartAt("review #code", base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = src.KizamiRepo.InsertInterval(&kokizami.Interval{KizamiID: paused.ID, StartedAt: base.Add(2 * time.Hour), StoppedAt: base.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = src.AddTags([]string{"#unused"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	e, err := src.Export()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = kokizami.WriteExport(buf, e)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	decoded, err := kokizami.ReadExport(buf)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(decoded, e); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	if e.Kizamis[1].StoppedAt != nil || len(e.Kizamis[1].Intervals) != 1 || e.Kizamis[1].Intervals[0].StoppedAt == nil {
+		t.Fatalf("unexpected result: [got] %v [want] an on-going kizami with a stopped interval", e.Kizamis[1])
+	}
+
+	// IDs are remapped
+	dst := newMemKokizami()
+	_, err = dst.Add("existing", base.Add(-time.Hour), base)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	r, err := dst.Import(decoded, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := &kokizami.ImportResult{Imported: 2, IDs: map[int]int{e.Kizamis[0].ID: 2, e.Kizamis[1].ID: 3}}
+	if diff := cmp.Diff(r, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	got, err := dst.Export()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ignore := cmpopts.IgnoreFields(kokizami.ExportKizami{}, "ID")
+	if diff := cmp.Diff(got.Kizamis[1:], e.Kizamis, ignore); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	if diff := cmp.Diff(got.Tags, e.Tags); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// duplicates are skipped
+	r, err = dst.Import(decoded, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(r, &kokizami.ImportResult{Skipped: 2, IDs: map[int]int{}}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// existing kizamis are deleted on replace
+	r, err = dst.Import(decoded, kokizami.ImportReplace)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if r.Imported != 2 || r.Skipped != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] 2 imported", r)
+	}
+	if diff := cmp.Diff(descs(t, dst), []string{"write #doc", "review #code"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// nothing is imported if any of kizamis is invalid
+	invalid := *decoded
+	invalid.Kizamis = append([]kokizami.ExportKizami{{ID: 100, Desc: "new", StartedAt: base.Add(5 * time.Hour)}},
+		kokizami.ExportKizami{ID: 101, Desc: "invalid", StartedAt: base.Add(6 * time.Hour), StoppedAt: &base})
+	_, err = dst.Import(&invalid, kokizami.ImportMerge)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	if diff := cmp.Diff(descs(t, dst), []string{"write #doc", "review #code"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// nor if any of tags is invalid
+	for i, tags := range [][]string{{"code"}, {"#a b"}, {"#"}} {
+		invalid := *decoded
+		invalid.Kizamis = []kokizami.ExportKizami{{ID: 100, Desc: "new", StartedAt: base.Add(5 * time.Hour), Tags: tags}}
+		_, err = dst.Import(&invalid, kokizami.ImportMerge)
+		if err == nil {
+			t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+		}
+
+		invalid = *decoded
+		invalid.Kizamis = []kokizami.ExportKizami{{ID: 100, Desc: "new", StartedAt: base.Add(5 * time.Hour)}}
+		invalid.Tags = tags
+		_, err = dst.Import(&invalid, kokizami.ImportMerge)
+		if err == nil {
+			t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+		}
+
+		if diff := cmp.Diff(descs(t, dst), []string{"write #doc", "review #code"}); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(tagLabels(t, dst), []string{"#doc", "#code", "#unused"}); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// kizamis are tagged by their desc rather than by tags in the document
+	foreign := *decoded
+	foreign.Kizamis = []kokizami.ExportKizami{{ID: 100, Desc: "new #doc", StartedAt: base.Add(5 * time.Hour), Tags: []string{"#other"}}}
+	r, err = dst.Import(&foreign, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ts, err := dst.TagsByKizamiID(r.IDs[100])
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 1 || ts[0].Label != "#doc" {
+		t.Fatalf("unexpected result: [got] %v [want] [#doc]", ts)
+	}
+	if diff := cmp.Diff(tagLabels(t, dst), []string{"#doc", "#code", "#unused"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	tcs := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: `{"version": 1, "kizamis": [], "tags": []}`},
+		{in: `{"version": 2, "kizamis": [], "tags": []}`, wantErr: true},
+		{in: `{"kizamis": [], "tags": []}`, wantErr: true},
+		{in: `[]`, wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		_, err := kokizami.ReadExport(strings.NewReader(tc.in))
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want error] %v", i, err, tc.wantErr)
+		}
+	}
+}
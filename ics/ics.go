@@ -0,0 +1,163 @@
+// Package ics converts kizamis from and to events of iCalendar (RFC 5545)
+// to overlay tracked time onto calendar apps and to import meetings in calendars.
+//
+// Each kizami becomes a VEVENT whose SUMMARY is desc of the kizami and
+// CATEGORIES are its tags without "#". Times are written in UTC.
+// On import, CATEGORIES of events are mapped to #tags and simple RRULEs are expanded.
+package ics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pankona/kokizami"
+)
+
+// timeFormat is the format of DATE-TIME in UTC
+const timeFormat = "20060102T150405Z"
+
+// prodID identifies the product that created calendars
+const prodID = "-//pankona//kokizami//EN"
+
+// maxLineOctets is the maximum length of a content line excluding line break
+const maxLineOctets = 75
+
+// Event represents a VEVENT of iCalendar
+type Event struct {
+	UID   string
+	Start time.Time
+	End   time.Time
+	// Stamp is the time the event was created in the calendar
+	Stamp      time.Time
+	Summary    string
+	Categories []string
+
+	// Following fields are read from calendars and not written.
+
+	// RRule is the recurrence rule of the event. It is empty if the event does not recur.
+	RRule string
+	// ExDates are start times of occurrences excluded from the recurrence
+	ExDates []time.Time
+	// RecurrenceID is the original start time of an occurrence of a recurring event
+	// that the event overrides. It is zero if the event is not an override.
+	RecurrenceID time.Time
+	// AllDay is true if the event starts at a date without time
+	AllDay bool
+	// Cancelled is true if the status of the event is CANCELLED
+	Cancelled bool
+}
+
+// UID returns UID of the event of a kizami.
+// It is stable as long as the kizami is in the same database.
+func UID(id int) string {
+	return fmt.Sprintf("kizami-%d@kokizami", id)
+}
+
+// KizamiID returns ID of the kizami whose event has the UID, or 0 if the UID is not returned by UID.
+func KizamiID(uid string) int {
+	s := strings.TrimSuffix(strings.TrimPrefix(uid, "kizami-"), "@kokizami")
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 || UID(id) != uid {
+		return 0
+	}
+	return id
+}
+
+// FromKizamis converts kizamis, e.g. results of Kokizami.List, into events.
+// Each kizami becomes an event from started time to stopped time including paused terms.
+// On-going kizamis end at now, which is also used as Stamp of events.
+func FromKizamis(ks []*kokizami.Kizami, now time.Time) []*Event {
+	ret := make([]*Event, len(ks))
+	for i, v := range ks {
+		e := &Event{
+			UID:        UID(v.ID),
+			Start:      v.StartedAt.UTC(),
+			End:        v.StoppedAt.UTC(),
+			Stamp:      now.UTC(),
+			Summary:    v.Desc,
+			Categories: []string{},
+		}
+		if v.StoppedAt.Unix() == 0 {
+			e.End = now.UTC()
+		}
+
+		seen := map[string]bool{}
+		for _, t := range kokizami.ExtractTags(v.Desc) {
+			if !seen[t] {
+				seen[t] = true
+				e.Categories = append(e.Categories, strings.TrimPrefix(t, "#"))
+			}
+		}
+		ret[i] = e
+	}
+	return ret
+}
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// fold folds a content line into lines of at most 75 octets without breaking UTF-8 characters.
+// Each line is terminated with CRLF.
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		b.WriteString(line[:i] + "\r\n ")
+		line = line[i:]
+		// continuation lines start with a space
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+// lines returns content lines of the event
+func (e *Event) lines() []string {
+	ls := []string{
+		"BEGIN:VEVENT",
+		"UID:" + escape(e.UID),
+		"DTSTAMP:" + e.Stamp.UTC().Format(timeFormat),
+		"DTSTART:" + e.Start.UTC().Format(timeFormat),
+		"DTEND:" + e.End.UTC().Format(timeFormat),
+		"SUMMARY:" + escape(e.Summary),
+	}
+	if len(e.Categories) > 0 {
+		cs := make([]string, len(e.Categories))
+		for i, v := range e.Categories {
+			cs[i] = escape(v)
+		}
+		ls = append(ls, "CATEGORIES:"+strings.Join(cs, ","))
+	}
+	return append(ls, "END:VEVENT")
+}
+
+// Write writes events as a VCALENDAR
+func Write(w io.Writer, es []*Event) error {
+	ls := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + prodID,
+		"CALSCALE:GREGORIAN",
+	}
+	for _, v := range es {
+		ls = append(ls, v.lines()...)
+	}
+	ls = append(ls, "END:VCALENDAR")
+
+	for _, v := range ls {
+		if _, err := io.WriteString(w, fold(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
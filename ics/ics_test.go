@@ -0,0 +1,224 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/memrepo"
+)
+
+func at(h, m int) time.Time {
+	return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+}
+
+func TestFromKizamis(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	ks := []*kokizami.Kizami{
+		{ID: 1, Desc: "review #code #code #client/acme", StartedAt: at(9, 0).In(jst), StoppedAt: at(10, 0).In(jst)},
+		{ID: 2, Desc: "lunch", StartedAt: at(12, 0), StoppedAt: time.Unix(0, 0).UTC()},
+	}
+
+	got := FromKizamis(ks, at(12, 30))
+	want := []*Event{
+		{
+			UID:        "kizami-1@kokizami",
+			Start:      at(9, 0),
+			End:        at(10, 0),
+			Stamp:      at(12, 30),
+			Summary:    "review #code #code #client/acme",
+			Categories: []string{"code", "client/acme"},
+		},
+		{
+			UID:        "kizami-2@kokizami",
+			Start:      at(12, 0),
+			End:        at(12, 30),
+			Stamp:      at(12, 30),
+			Summary:    "lunch",
+			Categories: []string{},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	es := []*Event{
+		{
+			UID:        "kizami-1@kokizami",
+			Start:      at(9, 0),
+			End:        at(10, 0),
+			Stamp:      at(12, 30),
+			Summary:    "review; a, b #code",
+			Categories: []string{"code", "client,acme"},
+		},
+		{
+			UID:     "kizami-2@kokizami",
+			Start:   at(12, 0),
+			End:     at(13, 0),
+			Stamp:   at(12, 30),
+			Summary: "lunch",
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := Write(buf, es)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//pankona//kokizami//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VEVENT",
+		"UID:kizami-1@kokizami",
+		"DTSTAMP:20190501T123000Z",
+		"DTSTART:20190501T090000Z",
+		"DTEND:20190501T100000Z",
+		`SUMMARY:review\; a\, b #code`,
+		`CATEGORIES:code,client\,acme`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:kizami-2@kokizami",
+		"DTSTAMP:20190501T123000Z",
+		"DTSTART:20190501T120000Z",
+		"DTEND:20190501T130000Z",
+		"SUMMARY:lunch",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want string
+	}{
+		{in: "SUMMARY:short", want: "SUMMARY:short\r\n"},
+		{
+			in:   "SUMMARY:" + strings.Repeat("a", 67+74+1),
+			want: "SUMMARY:" + strings.Repeat("a", 67) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			// multi-byte characters are not broken
+			in:   "SUMMARY:" + strings.Repeat("a", 66) + "あい",
+			want: "SUMMARY:" + strings.Repeat("a", 66) + "\r\n あい\r\n",
+		},
+	}
+
+	for i, tc := range tcs {
+		got := fold(tc.in)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %q [want] %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestKizamiID(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want int
+	}{
+		{in: "kizami-1@kokizami", want: 1},
+		{in: UID(123), want: 123},
+		{in: "kizami-0@kokizami", want: 0},
+		{in: "kizami-01@kokizami", want: 0},
+		{in: "kizami-+1@kokizami", want: 0},
+		{in: "kizami-1@example.com", want: 0},
+		{in: "a@example.com", want: 0},
+	}
+
+	for i, tc := range tcs {
+		got := KizamiID(tc.in)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	db := memrepo.NewDB()
+	k := &kokizami.Kokizami{
+		KizamiRepo: memrepo.NewKizamiRepo(db),
+		TagRepo:    memrepo.NewTagRepo(db),
+		TxRepo:     memrepo.NewTxRepo(db),
+		EventRepo:  memrepo.NewEventRepo(db),
+	}
+	for _, v := range []struct {
+		desc  string
+		start time.Time
+	}{
+		{desc: "review #code #client/acme", start: at(9, 0)},
+		{desc: "lunch", start: at(12, 0)},
+	} {
+		_, err := k.Add(v.desc, v.start, v.start.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	ks, err := k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	buf := &bytes.Buffer{}
+	err = Write(buf, FromKizamis(ks, at(13, 0)))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	es, err := Read(buf, time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ces, _ := ToCalendarEvents(es, time.Time{}, time.Time{})
+
+	// exported kizamis are not duplicated
+	result, err := k.ImportEvents(ces, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if result.Imported != 0 || result.Skipped != 2 {
+		t.Fatalf("unexpected result: [got] %+v [want] 2 skipped", result)
+	}
+
+	// but imported if they are deleted or moved
+	err = k.Delete(ks[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ks[1].StartedAt = at(11, 0)
+	_, err = k.Edit(ks[1])
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	result, err = k.ImportEvents(ces, kokizami.ImportMerge)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if result.Imported != 2 || result.Skipped != 0 {
+		t.Fatalf("unexpected result: [got] %+v [want] 2 imported", result)
+	}
+
+	ks, err = k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := []string{}
+	for _, v := range ks {
+		got = append(got, v.StartedAt.UTC().Format("15:04")+" "+v.Desc)
+	}
+	want := []string{"11:00 lunch", "09:00 review #code #client/acme", "12:00 lunch"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
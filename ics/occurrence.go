@@ -0,0 +1,132 @@
+package ics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// Skipped represents an event that is not imported because it is invalid
+type Skipped struct {
+	UID     string
+	Summary string
+	Reason  string
+}
+
+// overrideKey identifies an occurrence of a recurring event
+type overrideKey struct {
+	uid   string
+	start int64
+}
+
+// inRange returns true if t is in [since, until). Zero since and until mean unbounded.
+func inRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+// desc returns desc of kizamis imported from the event, that is the summary followed by #tags
+func (e *Event) desc() string {
+	return kokizami.ComposeDesc(e.Summary, e.Categories)
+}
+
+// occurrence returns an occurrence of the event that starts at start
+func (e *Event) occurrence(start time.Time, recurrenceID string) kokizami.CalendarEvent {
+	return kokizami.CalendarEvent{
+		UID:          e.UID,
+		RecurrenceID: recurrenceID,
+		KizamiID:     KizamiID(e.UID),
+		Desc:         e.desc(),
+		StartedAt:    start.UTC(),
+		StoppedAt:    start.Add(e.End.Sub(e.Start)).UTC(),
+	}
+}
+
+// invalid returns the reason why the event can not be imported, or empty string if it can
+func (e *Event) invalid() string {
+	if e.UID == "" {
+		return "UID is missing"
+	}
+	if !e.Start.Before(e.End) {
+		return "end must be after start"
+	}
+	return ""
+}
+
+// ToCalendarEvents converts events into occurrences that start in [since, until) to import as kizamis.
+// Zero since and until mean unbounded, though unbounded recurrences require until.
+// Recurring events are expanded by their RRULE except EXDATEs, and occurrences overridden by
+// events with RECURRENCE-ID are replaced with them. Occurrences of recurring events are
+// identified by their original start time in UTC as recurrence ID.
+// All-day and cancelled events are ignored since they are not tracked time.
+// Invalid events that may start in the range are returned as skipped.
+func ToCalendarEvents(es []*Event, since, until time.Time) ([]kokizami.CalendarEvent, []Skipped) {
+	ret := []kokizami.CalendarEvent{}
+	skipped := []Skipped{}
+	skip := func(e *Event, reason string) {
+		skipped = append(skipped, Skipped{UID: e.UID, Summary: e.Summary, Reason: reason})
+	}
+
+	overrides := map[overrideKey]bool{}
+	for _, v := range es {
+		if !v.RecurrenceID.IsZero() {
+			overrides[overrideKey{uid: v.UID, start: v.RecurrenceID.Unix()}] = true
+		}
+	}
+
+	for _, v := range es {
+		if v.AllDay || v.Cancelled {
+			continue
+		}
+		if v.RRule == "" {
+			if !inRange(v.Start, since, until) {
+				continue
+			}
+			if reason := v.invalid(); reason != "" {
+				skip(v, reason)
+				continue
+			}
+			rid := ""
+			if !v.RecurrenceID.IsZero() {
+				rid = v.RecurrenceID.UTC().Format(timeFormat)
+			}
+			ret = append(ret, v.occurrence(v.Start, rid))
+			continue
+		}
+
+		if !until.IsZero() && !v.Start.Before(until) {
+			continue
+		}
+		if reason := v.invalid(); reason != "" {
+			skip(v, reason)
+			continue
+		}
+		r, err := parseRRule(v.RRule, v.Start.Location())
+		if err != nil {
+			skip(v, err.Error())
+			continue
+		}
+		starts, err := r.starts(v.Start, until)
+		if err != nil {
+			skip(v, err.Error())
+			continue
+		}
+
+		exdates := map[int64]bool{}
+		for _, t := range v.ExDates {
+			exdates[t.Unix()] = true
+		}
+		for _, t := range starts {
+			if exdates[t.Unix()] || overrides[overrideKey{uid: v.UID, start: t.Unix()}] || !inRange(t, since, until) {
+				continue
+			}
+			ret = append(ret, v.occurrence(t, t.UTC().Format(timeFormat)))
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].StartedAt.Before(ret[j].StartedAt)
+	})
+
+	return ret, skipped
+}
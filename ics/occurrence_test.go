@@ -0,0 +1,171 @@
+package ics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func day(m, d, h int) time.Time {
+	return time.Date(2019, time.Month(m), d, h, 0, 0, 0, time.UTC)
+}
+
+func TestRRuleStarts(t *testing.T) {
+	tcs := []struct {
+		inRRule string
+		inStart time.Time
+		inLimit time.Time
+		want    []time.Time
+		wantErr bool
+	}{
+		{
+			inRRule: "FREQ=DAILY;COUNT=3",
+			inStart: day(5, 1, 9),
+			want:    []time.Time{day(5, 1, 9), day(5, 2, 9), day(5, 3, 9)},
+		},
+		{
+			// 2019-05-03 is Friday
+			inRRule: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20190507",
+			inStart: day(5, 3, 9),
+			want:    []time.Time{day(5, 3, 9), day(5, 6, 9), day(5, 7, 9)},
+		},
+		{
+			// 2019-05-01 is Wednesday
+			inRRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			inStart: day(5, 1, 9),
+			inLimit: day(5, 28, 0),
+			want:    []time.Time{day(5, 1, 9), day(5, 13, 9), day(5, 15, 9), day(5, 27, 9)},
+		},
+		{
+			inRRule: "FREQ=WEEKLY;UNTIL=20190515T090000Z",
+			inStart: day(5, 1, 9),
+			want:    []time.Time{day(5, 1, 9), day(5, 8, 9), day(5, 15, 9)},
+		},
+		{
+			// months without 31st are skipped
+			inRRule: "FREQ=MONTHLY;COUNT=3",
+			inStart: day(1, 31, 9),
+			want:    []time.Time{day(1, 31, 9), day(3, 31, 9), day(5, 31, 9)},
+		},
+		{
+			inRRule: "FREQ=YEARLY;INTERVAL=2;COUNT=2",
+			inStart: day(5, 1, 9),
+			want:    []time.Time{day(5, 1, 9), time.Date(2021, 5, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{inRRule: "FREQ=DAILY", inStart: day(5, 1, 9), wantErr: true},
+		{inRRule: "FREQ=HOURLY;COUNT=2", inStart: day(5, 1, 9), wantErr: true},
+		{inRRule: "FREQ=MONTHLY;BYDAY=1MO;COUNT=2", inStart: day(5, 1, 9), wantErr: true},
+		{inRRule: "FREQ=MONTHLY;BYMONTHDAY=1;COUNT=2", inStart: day(5, 1, 9), wantErr: true},
+		{inRRule: "COUNT=2", inStart: day(5, 1, 9), wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		var got []time.Time
+		r, err := parseRRule(tc.inRRule, time.UTC)
+		if err == nil {
+			got, err = r.starts(tc.inStart, tc.inLimit)
+		}
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestRRuleKeepsLocalTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// daylight saving time starts on 2019-03-10
+	r, err := parseRRule("FREQ=WEEKLY;COUNT=2", ny)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got, err := r.starts(time.Date(2019, 3, 4, 9, 0, 0, 0, ny), time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := []time.Time{day(3, 4, 14), day(3, 11, 13)}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestToCalendarEvents(t *testing.T) {
+	es := []*Event{
+		{
+			UID:        "a@example.com",
+			Start:      day(5, 1, 9),
+			End:        day(5, 1, 10),
+			Summary:    "sync #team",
+			Categories: []string{"team", "client acme"},
+			RRule:      "FREQ=DAILY",
+			ExDates:    []time.Time{day(5, 2, 9)},
+		},
+		{
+			// moved occurrence
+			UID:          "a@example.com",
+			Start:        day(5, 3, 15),
+			End:          day(5, 3, 16),
+			Summary:      "sync (moved)",
+			RecurrenceID: day(5, 3, 9),
+		},
+		{
+			// cancelled occurrence
+			UID:          "a@example.com",
+			Start:        day(5, 4, 9),
+			End:          day(5, 4, 10),
+			RecurrenceID: day(5, 4, 9),
+			Cancelled:    true,
+		},
+		{UID: "b@example.com", Start: day(5, 3, 12), End: day(5, 3, 13)},
+		{UID: "c@example.com", Start: day(5, 3, 0), End: day(5, 4, 0), Summary: "holiday", AllDay: true},
+		{UID: "d@example.com", Start: day(4, 30, 9), End: day(4, 30, 10), Summary: "out of range"},
+		{Start: day(5, 3, 9), End: day(5, 3, 10), Summary: "no uid"},
+		{UID: "e@example.com", Start: day(5, 3, 9), End: day(5, 3, 9), Summary: "no duration"},
+		{UID: "f@example.com", Start: day(5, 1, 9), End: day(5, 1, 10), Summary: "hourly", RRule: "FREQ=HOURLY"},
+	}
+
+	got, skipped := ToCalendarEvents(es, day(5, 1, 0), day(5, 5, 0))
+	want := []kokizami.CalendarEvent{
+		{
+			UID:          "a@example.com",
+			RecurrenceID: "20190501T090000Z",
+			Desc:         "sync #team #client_acme",
+			StartedAt:    day(5, 1, 9),
+			StoppedAt:    day(5, 1, 10),
+		},
+		{UID: "b@example.com", Desc: kokizami.Untitled, StartedAt: day(5, 3, 12), StoppedAt: day(5, 3, 13)},
+		{
+			UID:          "a@example.com",
+			RecurrenceID: "20190503T090000Z",
+			Desc:         "sync (moved)",
+			StartedAt:    day(5, 3, 15),
+			StoppedAt:    day(5, 3, 16),
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	wantSkipped := []Skipped{
+		{Summary: "no uid", Reason: "UID is missing"},
+		{UID: "e@example.com", Summary: "no duration", Reason: "end must be after start"},
+		{UID: "f@example.com", Summary: "hourly", Reason: "unsupported RRULE frequency [HOURLY]"},
+	}
+	if diff := cmp.Diff(skipped, wantSkipped); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
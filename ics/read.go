@@ -0,0 +1,296 @@
+package ics
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentLine is an unfolded content line with the line number where it starts
+type contentLine struct {
+	line int
+	text string
+}
+
+// unfold joins folded lines, that start with a space or a tab, with preceding lines
+func unfold(s string) []contentLine {
+	s = strings.TrimPrefix(s, "\ufeff")
+	ret := []contentLine{}
+	for n, v := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(v, " ") || strings.HasPrefix(v, "\t")) && len(ret) > 0 {
+			ret[len(ret)-1].text += v[1:]
+			continue
+		}
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		ret = append(ret, contentLine{line: n + 1, text: v})
+	}
+	return ret
+}
+
+// property is a parsed content line, NAME;PARAM=VALUE:VALUE
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty parses a content line. Names of properties and parameters are upper-cased.
+func parseProperty(line string) (*property, error) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("':' is not found in [%s]", line)
+	}
+
+	p := &property{params: map[string]string{}, value: line[colon+1:]}
+	var parts []string
+	quoted = false
+	start := 0
+	head := line[:colon]
+	for i, r := range head {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ';' && !quoted {
+			parts = append(parts, head[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, head[start:])
+
+	p.name = strings.ToUpper(parts[0])
+	for _, v := range parts[1:] {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid parameter [%s] of %s", v, p.name)
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+// unescape unescapes a TEXT value
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitValues splits a value into a list separated by unescaped ","
+func splitValues(s string) []string {
+	ret := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			ret = append(ret, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(ret, s[start:])
+}
+
+// parseTime parses DATE or DATE-TIME value of a property.
+// Times with TZID are in the time zone, or in loc if it is unknown. Floating times are in loc.
+func parseTime(value string, params map[string]string, loc *time.Location) (t time.Time, date bool, err error) {
+	if tzid, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date [%s]", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(timeFormat, value)
+	} else {
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date-time [%s]", value)
+	}
+	return t, false, nil
+}
+
+// durationPattern matches DURATION value, e.g. PT1H30M or P1D
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses DURATION value
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid duration [%s]", value)
+	}
+
+	var d time.Duration
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, u := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration [%s]", value)
+		}
+		d += time.Duration(n) * u
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// eventBuilder builds an event from properties of a VEVENT
+type eventBuilder struct {
+	e        *Event
+	end      bool
+	duration *time.Duration
+}
+
+// set sets a property to the event
+func (b *eventBuilder) set(p *property, loc *time.Location) error {
+	var err error
+	switch p.name {
+	case "UID":
+		b.e.UID = p.value
+	case "SUMMARY":
+		b.e.Summary = unescape(p.value)
+	case "CATEGORIES":
+		for _, v := range splitValues(p.value) {
+			b.e.Categories = append(b.e.Categories, unescape(v))
+		}
+	case "DTSTART":
+		b.e.Start, b.e.AllDay, err = parseTime(p.value, p.params, loc)
+	case "DTEND":
+		b.e.End, _, err = parseTime(p.value, p.params, loc)
+		b.end = true
+	case "DURATION":
+		var d time.Duration
+		d, err = parseDuration(p.value)
+		b.duration = &d
+	case "DTSTAMP":
+		b.e.Stamp, _, err = parseTime(p.value, p.params, loc)
+	case "RRULE":
+		b.e.RRule = p.value
+	case "EXDATE":
+		for _, v := range splitValues(p.value) {
+			var t time.Time
+			t, _, err = parseTime(v, p.params, loc)
+			if err != nil {
+				break
+			}
+			b.e.ExDates = append(b.e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		b.e.RecurrenceID, _, err = parseTime(p.value, p.params, loc)
+	case "STATUS":
+		b.e.Cancelled = strings.EqualFold(p.value, "CANCELLED")
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", p.name, err)
+	}
+	return nil
+}
+
+// build returns the event. End is derived from DURATION if DTEND is absent,
+// or is the next day of start for all-day events.
+func (b *eventBuilder) build() *Event {
+	switch {
+	case b.end:
+	case b.duration != nil:
+		b.e.End = b.e.Start.Add(*b.duration)
+	case b.e.AllDay:
+		b.e.End = b.e.Start.AddDate(0, 0, 1)
+	default:
+		b.e.End = b.e.Start
+	}
+	return b.e
+}
+
+// Read reads VEVENTs in a calendar. DATE-TIME values with TZID are read in the time zone,
+// and floating ones or ones with unknown TZID are read in loc.
+// Other components like VTODO and VTIMEZONE are ignored.
+// Error is returned with the line number if the calendar is malformed.
+func Read(r io.Reader, loc *time.Location) ([]*Event, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*Event{}
+	var b *eventBuilder
+	// nested is the number of components nested in VEVENT, e.g. VALARM
+	nested := 0
+	for _, v := range unfold(string(bs)) {
+		p, err := parseProperty(v.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", v.line, err)
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			if b != nil {
+				return nil, fmt.Errorf("line %d: VEVENT is nested", v.line)
+			}
+			b = &eventBuilder{e: &Event{}}
+		case b == nil:
+			continue
+		case p.name == "BEGIN":
+			nested++
+		case p.name == "END" && nested > 0:
+			nested--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			ret = append(ret, b.build())
+			b = nil
+		case nested == 0:
+			if err := b.set(p, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %v", v.line, err)
+			}
+		}
+	}
+	if b != nil {
+		return nil, fmt.Errorf("VEVENT is not terminated")
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,145 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRead(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	jst := time.FixedZone("JST", 9*60*60)
+
+	in := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Asia/Tokyo",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:a@example.com",
+		"DTSTART;TZID=Asia/Tokyo:20190501T180000",
+		"DTEND;TZID=Asia/Tokyo:20190501T190000",
+		`SUMMARY:weekly sync\, with "team"; and `,
+		" others",
+		`CATEGORIES:team,client\,acme`,
+		"CATEGORIES:meeting",
+		"RRULE:FREQ=WEEKLY;COUNT=3",
+		"EXDATE;TZID=Asia/Tokyo:20190508T180000,20190515T180000",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"SUMMARY:alarm",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b@example.com",
+		"RECURRENCE-ID:20190508T090000Z",
+		"DTSTART:20190508T100000",
+		"DURATION:PT1H30M",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:c@example.com",
+		"DTSTART;VALUE=DATE:20190501",
+		"SUMMARY:holiday",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:d@example.com",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	got, err := Read(strings.NewReader(in), jst)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := []*Event{
+		{
+			UID:        "a@example.com",
+			Start:      time.Date(2019, 5, 1, 18, 0, 0, 0, tokyo),
+			End:        time.Date(2019, 5, 1, 19, 0, 0, 0, tokyo),
+			Summary:    `weekly sync, with "team"; and others`,
+			Categories: []string{"team", "client,acme", "meeting"},
+			RRule:      "FREQ=WEEKLY;COUNT=3",
+			ExDates: []time.Time{
+				time.Date(2019, 5, 8, 18, 0, 0, 0, tokyo),
+				time.Date(2019, 5, 15, 18, 0, 0, 0, tokyo),
+			},
+		},
+		{
+			UID:          "b@example.com",
+			Start:        time.Date(2019, 5, 8, 10, 0, 0, 0, jst),
+			End:          time.Date(2019, 5, 8, 11, 30, 0, 0, jst),
+			RecurrenceID: time.Date(2019, 5, 8, 9, 0, 0, 0, time.UTC),
+			Cancelled:    true,
+		},
+		{
+			UID:     "c@example.com",
+			Start:   time.Date(2019, 5, 1, 0, 0, 0, 0, jst),
+			End:     time.Date(2019, 5, 2, 0, 0, 0, 0, jst),
+			Summary: "holiday",
+			AllDay:  true,
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tcs := []struct {
+		in string
+	}{
+		{in: "BEGIN:VEVENT\nUID:a\n"},
+		{in: "BEGIN:VEVENT\nBEGIN:VEVENT\nEND:VEVENT\nEND:VEVENT\n"},
+		{in: "BEGIN:VEVENT\nDTSTART:2019-05-01\nEND:VEVENT\n"},
+		{in: "BEGIN:VEVENT\nDURATION:1h\nEND:VEVENT\n"},
+		{in: "BEGIN:VEVENT\nno colon\nEND:VEVENT\n"},
+	}
+
+	for i, tc := range tcs {
+		_, err := Read(strings.NewReader(tc.in), time.UTC)
+		if err == nil {
+			t.Fatalf("[No.%d] unexpected result: [got] nil [want] error", i)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tcs := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "PT1H30M", want: 90 * time.Minute},
+		{in: "P1DT2H", want: 26 * time.Hour},
+		{in: "P1W", want: 7 * 24 * time.Hour},
+		{in: "-PT15M", want: -15 * time.Minute},
+		{in: "PT", wantErr: true},
+		{in: "P", wantErr: true},
+		{in: "1H", wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		got, err := parseDuration(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
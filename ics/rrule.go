@@ -0,0 +1,176 @@
+package ics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods limits periods of a recurrence to expand, that is over 270 years of daily events
+const maxPeriods = 100000
+
+// weekdays maps weekdays in RRULE to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// rrule represents a simple recurrence rule.
+// FREQ, INTERVAL, COUNT, UNTIL and WKST are supported, and BYDAY is supported
+// without ordinals for DAILY and WEEKLY rules.
+type rrule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+	wkst     time.Weekday
+}
+
+// parseRRule parses RRULE value. UNTIL without time zone is read in loc.
+func parseRRule(value string, loc *time.Location) (*rrule, error) {
+	r := &rrule{interval: 1, wkst: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part [%s]", part)
+		}
+
+		var err error
+		switch k, v := strings.ToUpper(kv[0]), strings.ToUpper(kv[1]); k {
+		case "FREQ":
+			if v != "DAILY" && v != "WEEKLY" && v != "MONTHLY" && v != "YEARLY" {
+				return nil, fmt.Errorf("unsupported RRULE frequency [%s]", v)
+			}
+			r.freq = v
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(v)
+			if err != nil || r.interval < 1 {
+				return nil, fmt.Errorf("invalid RRULE interval [%s]", v)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+			if err != nil || r.count < 1 {
+				return nil, fmt.Errorf("invalid RRULE count [%s]", v)
+			}
+		case "UNTIL":
+			var date bool
+			r.until, date, err = parseTime(v, map[string]string{}, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE until [%s]", v)
+			}
+			// UNTIL is inclusive
+			if date {
+				r.until = r.until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE weekday [%s]", d)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "WKST":
+			wd, ok := weekdays[v]
+			if !ok {
+				return nil, fmt.Errorf("invalid RRULE week start [%s]", v)
+			}
+			r.wkst = wd
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part [%s]", k)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE frequency is missing")
+	}
+	if len(r.byDay) > 0 && r.freq != "DAILY" && r.freq != "WEEKLY" {
+		return nil, fmt.Errorf("unsupported RRULE BYDAY for %s", r.freq)
+	}
+	return r, nil
+}
+
+// hasDay returns true if BYDAY is not specified or contains the weekday
+func (r *rrule) hasDay(wd time.Weekday) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, v := range r.byDay {
+		if v == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// period returns candidates of start times in i-th period from dtstart in order
+func (r *rrule) period(dtstart time.Time, i int) []time.Time {
+	n := i * r.interval
+	switch r.freq {
+	case "DAILY":
+		t := dtstart.AddDate(0, 0, n)
+		if !r.hasDay(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return []time.Time{dtstart.AddDate(0, 0, 7*n)}
+		}
+		offset := (int(dtstart.Weekday()) - int(r.wkst) + 7) % 7
+		weekStart := dtstart.AddDate(0, 0, 7*n-offset)
+		ret := []time.Time{}
+		for _, wd := range r.byDay {
+			ret = append(ret, weekStart.AddDate(0, 0, (int(wd)-int(r.wkst)+7)%7))
+		}
+		sort.Slice(ret, func(i, j int) bool { return ret[i].Before(ret[j]) })
+		return ret
+	case "MONTHLY":
+		// months that do not have the day are skipped
+		t := dtstart.AddDate(0, n, 0)
+		if t.Day() != dtstart.Day() {
+			return nil
+		}
+		return []time.Time{t}
+	case "YEARLY":
+		t := dtstart.AddDate(n, 0, 0)
+		if t.Day() != dtstart.Day() {
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
+
+// starts returns start times of occurrences from dtstart that start before limit.
+// Zero limit means no limit, that is allowed only if COUNT or UNTIL is specified.
+func (r *rrule) starts(dtstart, limit time.Time) ([]time.Time, error) {
+	if limit.IsZero() && r.count == 0 && r.until.IsZero() {
+		return nil, fmt.Errorf("unbounded recurrence requires end of range")
+	}
+
+	ret := []time.Time{}
+	n := 0
+	for i := 0; i < maxPeriods; i++ {
+		for _, t := range r.period(dtstart, i) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if (r.count > 0 && n >= r.count) || (!r.until.IsZero() && t.After(r.until)) ||
+				(!limit.IsZero() && !t.Before(limit)) {
+				return ret, nil
+			}
+			n++
+			ret = append(ret, t)
+		}
+	}
+	return ret, nil
+}
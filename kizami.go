@@ -10,10 +10,46 @@ type Kizami struct {
 	Desc      string
 	StartedAt time.Time
 	StoppedAt time.Time
+	// Intervals are terms that this Kizami is actually on-going.
+	// Intervals are recorded only if this Kizami has been paused.
+	Intervals []Interval
 }
 
-// Elapsed returns kizami's elapsed time
+// Interval represents a term that a Kizami is actually on-going
+type Interval struct {
+	ID        int
+	KizamiID  int
+	StartedAt time.Time
+	StoppedAt time.Time
+}
+
+// Elapsed returns interval's elapsed time
+func (i *Interval) Elapsed() time.Duration {
+	var elapsed time.Duration
+	if i.StoppedAt.Unix() == 0 {
+		// this Interval is on going. Show elapsed time until now.
+		now := time.Now().UTC()
+		elapsed = now.Sub(i.StartedAt)
+	} else {
+		elapsed = i.StoppedAt.Sub(i.StartedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+	}
+	return elapsed
+}
+
+// Elapsed returns kizami's elapsed time.
+// Paused terms are not counted if this Kizami has intervals.
 func (k *Kizami) Elapsed() time.Duration {
+	if len(k.Intervals) > 0 {
+		var elapsed time.Duration
+		for i := range k.Intervals {
+			elapsed += k.Intervals[i].Elapsed()
+		}
+		return elapsed
+	}
+
 	var elapsed time.Duration
 	if k.StoppedAt.Unix() == 0 {
 		// this Kizami is on going. Show elapsed time until now.
@@ -28,14 +64,83 @@ func (k *Kizami) Elapsed() time.Duration {
 	return elapsed
 }
 
+// Paused returns true if this Kizami is paused
+func (k *Kizami) Paused() bool {
+	if k.StoppedAt.Unix() != 0 || len(k.Intervals) == 0 {
+		return false
+	}
+	return k.openInterval() == nil
+}
+
+// openInterval returns an on-going interval of this Kizami.
+// nil is returned if there is no on-going interval.
+func (k *Kizami) openInterval() *Interval {
+	for i := range k.Intervals {
+		if k.Intervals[i].StoppedAt.Unix() == 0 {
+			return &k.Intervals[i]
+		}
+	}
+	return nil
+}
+
+// KizamiOrder specifies order of kizamis found by KizamiQuery
+type KizamiOrder int
+
+const (
+	// OrderByID orders kizamis by ID
+	OrderByID KizamiOrder = iota
+	// OrderByStartedAt orders kizamis by started time
+	OrderByStartedAt
+	// OrderByStoppedAt orders kizamis by stopped time. On-going kizamis come last.
+	OrderByStoppedAt
+	// OrderByElapsed orders kizamis by elapsed time except paused terms
+	OrderByElapsed
+	// OrderByDesc orders kizamis by desc
+	OrderByDesc
+)
+
+// KizamiQuery represents conditions to find kizamis
+type KizamiQuery struct {
+	// Since and Until specify half-open range [Since, Until) that kizamis overlap.
+	// Zero value means unbounded. On-going kizamis are treated as they continue forever.
+	Since time.Time
+	Until time.Time
+	// Tags are labels of tags that kizamis must have all of.
+	// A tag matches its descendants, e.g. #a matches #a/b. Labels are case-sensitive.
+	Tags []string
+	// ExcludeTags are labels of tags that kizamis must not have any of,
+	// including their descendants
+	ExcludeTags []string
+	// Desc is a substring that desc of kizamis must contain.
+	// It is matched case-insensitively for ASCII letters.
+	Desc string
+	// Running specifies to find only on-going kizamis including paused ones
+	Running bool
+	// Now is used as stopped time of on-going kizamis to order them.
+	// Kokizami.Find sets current time.
+	Now time.Time
+	// OrderBy specifies order of kizamis. Ties are ordered by ID.
+	OrderBy    KizamiOrder
+	Descending bool
+	// Limit is the maximum number of kizamis. 0 means no limit.
+	Limit  int
+	Offset int
+}
+
 // KizamiRepository is an interface to fetch Kizami from repository
 type KizamiRepository interface {
 	FindAll() ([]*Kizami, error)
 	Insert(desc string) (*Kizami, error)
+	InsertWithTime(desc string, startedAt, stoppedAt time.Time) (*Kizami, error)
 	Update(k *Kizami) error
 	Delete(k *Kizami) error
 	FindByID(id int) (*Kizami, error)
 	FindByStoppedAt(t time.Time) ([]*Kizami, error)
+	FindOverlapped(startedAt, stoppedAt time.Time) ([]*Kizami, error)
+	Find(q *KizamiQuery) ([]*Kizami, error)
 	Tagging(kizamiID int, tagIDs []int) error
 	Untagging(kizamiID int) error
+	InsertInterval(i *Interval) error
+	UpdateInterval(i *Interval) error
+	DeleteInterval(i *Interval) error
 }
@@ -1,21 +1,60 @@
 package kokizami
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 	"time"
-
-	// go-sqlite3 is imported only here
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // Kokizami represents a instance of kokizami
 // Kokizami provides most APIs of kokizami library
 type Kokizami struct {
-	now func() time.Time
+	// Now returns current time. time.Now is used if nil.
+	Now func() time.Time
+	// DB is the database that repositories operate on.
+	// It is set by sqlite.Open and closed by Close.
+	DB *sql.DB
 
 	KizamiRepo  KizamiRepository
 	TagRepo     TagRepository
 	SummaryRepo SummaryRepository
+	// SearchRepo is used to search kizamis by full-text.
+	// Search is not available if nil.
+	SearchRepo SearchRepository
+	// EventRepo is used to track calendar events imported as kizamis.
+	// Import of calendar events is not available if nil.
+	EventRepo EventRepository
+	// TxRepo is used to make operations atomic.
+	// Operations are not atomic if nil.
+	TxRepo TransactionRepository
+
+	// Location is used to bucket kizamis into days, weeks and months.
+	// time.Local is used if nil.
+	Location *time.Location
+
+	// OverlapPolicy specifies how to treat a kizami that overlaps others
+	// on Start, Add and Edit.
+	OverlapPolicy OverlapPolicy
+	// OnOverlap is called with overlapped kizamis if OverlapPolicy is OverlapWarn
+	OnOverlap func(k *Kizami, overlapped []*Kizami)
+}
+
+// timeNow returns current time.
+// time.Now is used if no clock is injected.
+func (k *Kokizami) timeNow() time.Time {
+	if k.Now == nil {
+		return time.Now()
+	}
+	return k.Now()
+}
+
+// Close closes DB. It does nothing if DB is nil.
+func (k *Kokizami) Close() error {
+	if k.DB == nil {
+		return nil
+	}
+	return k.DB.Close()
 }
 
 // initialTime is used to insert a time value that indicates initial value of time.
@@ -27,13 +66,61 @@ func initialTime() time.Time {
 	return t.UTC()
 }
 
-// Start starts a new kizami with specified desc
+// Start starts a new kizami with specified desc.
+// The kizami is tagged with tags contained in desc.
 func (k *Kokizami) Start(desc string) (*Kizami, error) {
+	return k.StartAt(desc, k.timeNow())
+}
+
+// StartAt starts a new kizami with specified desc at specified time.
+// The kizami is tagged with tags contained in desc.
+func (k *Kokizami) StartAt(desc string, at time.Time) (*Kizami, error) {
+	if len(desc) == 0 {
+		return nil, fmt.Errorf("desc must not be empty")
+	}
+
+	return k.insert(desc, at.UTC(), initialTime())
+}
+
+// insert inserts a new kizami and tags it in a transaction
+func (k *Kokizami) insert(desc string, startedAt, stoppedAt time.Time) (*Kizami, error) {
+	var ret *Kizami
+	err := k.Transaction(func(tx *Kokizami) error {
+		err := tx.checkOverlap(&Kizami{
+			Desc:      desc,
+			StartedAt: startedAt,
+			StoppedAt: stoppedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		ret, err = tx.KizamiRepo.InsertWithTime(desc, startedAt, stoppedAt)
+		if err != nil {
+			return err
+		}
+
+		return tx.retag(ret.ID, desc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Add adds a new kizami that has been already stopped with specified desc and time.
+// The kizami is tagged with tags contained in desc.
+func (k *Kokizami) Add(desc string, startedAt, stoppedAt time.Time) (*Kizami, error) {
 	if len(desc) == 0 {
 		return nil, fmt.Errorf("desc must not be empty")
 	}
 
-	return k.KizamiRepo.Insert(desc)
+	if !startedAt.Before(stoppedAt) {
+		return nil, fmt.Errorf("stopped_at (%v) must be after started_at (%v)", stoppedAt, startedAt)
+	}
+
+	return k.insert(desc, startedAt.UTC(), stoppedAt.UTC())
 }
 
 // Get returns a Kizami by specified ID
@@ -41,58 +128,252 @@ func (k *Kokizami) Get(id int) (*Kizami, error) {
 	return k.KizamiRepo.FindByID(id)
 }
 
-// Edit edits a specified kizami and update its model
+// Edit edits a specified kizami and update its model.
+// The kizami is retagged with tags contained in its desc.
 func (k *Kokizami) Edit(ki *Kizami) (*Kizami, error) {
-	m, err := k.KizamiRepo.FindByID(ki.ID)
+	var ret *Kizami
+	err := k.Transaction(func(tx *Kokizami) error {
+		m, err := tx.KizamiRepo.FindByID(ki.ID)
+		if err != nil {
+			return err
+		}
+
+		startedAt, stoppedAt := m.StartedAt, m.StoppedAt
+		m.Desc = ki.Desc
+		m.StartedAt = ki.StartedAt.UTC()
+		m.StoppedAt = ki.StoppedAt.UTC()
+
+		err = tx.checkOverlap(m)
+		if err != nil {
+			return err
+		}
+
+		err = tx.KizamiRepo.Update(m)
+		if err != nil {
+			return err
+		}
+
+		err = tx.fitIntervals(m, startedAt, stoppedAt)
+		if err != nil {
+			return err
+		}
+
+		err = tx.retag(m.ID, m.Desc)
+		if err != nil {
+			return err
+		}
+
+		ret, err = tx.KizamiRepo.FindByID(ki.ID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	m.Desc = ki.Desc
-	m.StartedAt = ki.StartedAt.UTC()
-	m.StoppedAt = ki.StoppedAt.UTC()
+	return ret, nil
+}
 
-	err = k.KizamiRepo.Update(m)
+// retag replaces tags of specified kizami with tags contained in desc
+func (k *Kokizami) retag(kizamiID int, desc string) error {
+	err := k.KizamiRepo.Untagging(kizamiID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return k.KizamiRepo.FindByID(ki.ID)
-}
+	labels := ExtractTags(desc)
+	if len(labels) == 0 {
+		return nil
+	}
 
-// Stop stops a on-going kizami by specified ID
-func (k *Kokizami) Stop(id int) error {
-	ki, err := k.KizamiRepo.FindByID(id)
+	err = k.TagRepo.Insert(labels)
+	if err != nil {
+		return err
+	}
+
+	ts, err := k.TagRepo.FindByLabels(labels)
 	if err != nil {
 		return err
 	}
-	ki.StoppedAt = k.now().UTC()
-	return k.KizamiRepo.Update(ki)
+
+	tagIDs := make([]int, len(ts))
+	for i, v := range ts {
+		tagIDs[i] = v.ID
+	}
+
+	return k.KizamiRepo.Tagging(kizamiID, tagIDs)
+}
+
+// Stop stops a on-going kizami by specified ID
+func (k *Kokizami) Stop(id int) error {
+	return k.StopAt(id, k.timeNow())
+}
+
+// StopAt stops a on-going kizami by specified ID at specified time
+func (k *Kokizami) StopAt(id int, at time.Time) error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ki, err := tx.KizamiRepo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		return tx.stop(ki, at.UTC())
+	})
 }
 
 // StopAll stops all on-going kizamis
 func (k *Kokizami) StopAll() error {
-	ks, err := k.KizamiRepo.FindByStoppedAt(initialTime())
-	if err != nil {
+	return k.StopAllAt(k.timeNow())
+}
+
+// StopAllAt stops all on-going kizamis at specified time
+func (k *Kokizami) StopAllAt(at time.Time) error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ks, err := tx.KizamiRepo.FindByStoppedAt(initialTime())
+		if err != nil {
+			return err
+		}
+		for i := range ks {
+			if err := tx.stop(ks[i], at.UTC()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (k *Kokizami) stop(ki *Kizami, at time.Time) error {
+	if at.Before(ki.StartedAt) {
+		return fmt.Errorf("kizami [%d] can not be stopped at %v before it started", ki.ID, at)
+	}
+
+	startedAt, stoppedAt := ki.StartedAt, ki.StoppedAt
+	ki.StoppedAt = at
+	if err := k.KizamiRepo.Update(ki); err != nil {
 		return err
 	}
-	now := k.now().UTC()
-	for i := range ks {
-		ks[i].StoppedAt = now
-		if err := k.KizamiRepo.Update(ks[i]); err != nil {
+	return k.fitIntervals(ki, startedAt, stoppedAt)
+}
+
+// fitIntervals fits intervals of specified kizami into its range
+// after its started_at and stopped_at are changed from startedAt and stoppedAt.
+// Intervals that started or stopped together with the kizami follow the new time,
+// the others are clipped to the range, and intervals that become empty are deleted.
+// An on-going interval is stopped when the kizami is stopped.
+func (k *Kokizami) fitIntervals(ki *Kizami, startedAt, stoppedAt time.Time) error {
+	running := isRunning(ki)
+	is := append([]Interval{}, ki.Intervals...)
+	for _, v := range is {
+		i := v
+		if i.StartedAt.Equal(startedAt) || i.StartedAt.Before(ki.StartedAt) {
+			i.StartedAt = ki.StartedAt
+		}
+		if i.StoppedAt.Equal(stoppedAt) || (!running && (i.StoppedAt.Unix() == 0 || i.StoppedAt.After(ki.StoppedAt))) {
+			i.StoppedAt = ki.StoppedAt
+		}
+		if i == v {
+			continue
+		}
+
+		if i.StoppedAt.Unix() != 0 && !i.StartedAt.Before(i.StoppedAt) {
+			if err := k.KizamiRepo.DeleteInterval(&i); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := k.KizamiRepo.UpdateInterval(&i); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// Delete deletes a kizami by specified ID
-func (k *Kokizami) Delete(id int) error {
-	ki, err := k.KizamiRepo.FindByID(id)
-	if err != nil {
-		return err
+// Pause pauses a on-going kizami by specified ID.
+// Paused kizami can be resumed by Resume.
+func (k *Kokizami) Pause(id int) error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ki, err := tx.KizamiRepo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		return tx.pause(ki, k.timeNow().UTC())
+	})
+}
+
+// PauseAll pauses all on-going kizamis
+func (k *Kokizami) PauseAll() error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ks, err := tx.KizamiRepo.FindByStoppedAt(initialTime())
+		if err != nil {
+			return err
+		}
+		now := k.timeNow().UTC()
+		for i := range ks {
+			if ks[i].Paused() {
+				continue
+			}
+			if err := tx.pause(ks[i], now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (k *Kokizami) pause(ki *Kizami, at time.Time) error {
+	if ki.StoppedAt.Unix() != 0 {
+		return fmt.Errorf("kizami [%d] has already been stopped", ki.ID)
 	}
-	return k.KizamiRepo.Delete(ki)
+	if ki.Paused() {
+		return fmt.Errorf("kizami [%d] has already been paused", ki.ID)
+	}
+
+	if len(ki.Intervals) == 0 {
+		// record the first interval that starts when this kizami started
+		return k.KizamiRepo.InsertInterval(&Interval{
+			KizamiID:  ki.ID,
+			StartedAt: ki.StartedAt.UTC(),
+			StoppedAt: at,
+		})
+	}
+
+	i := ki.openInterval()
+	i.StoppedAt = at
+	return k.KizamiRepo.UpdateInterval(i)
+}
+
+// Resume resumes a paused kizami by specified ID
+func (k *Kokizami) Resume(id int) error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ki, err := tx.KizamiRepo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if !ki.Paused() {
+			return fmt.Errorf("kizami [%d] is not paused", ki.ID)
+		}
+
+		return tx.KizamiRepo.InsertInterval(&Interval{
+			KizamiID:  ki.ID,
+			StartedAt: k.timeNow().UTC(),
+			StoppedAt: initialTime(),
+		})
+	})
+}
+
+// Delete deletes a kizami by specified ID with its relations to tags
+func (k *Kokizami) Delete(id int) error {
+	return k.Transaction(func(tx *Kokizami) error {
+		ki, err := tx.KizamiRepo.FindByID(id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.KizamiRepo.Untagging(ki.ID)
+		if err != nil {
+			return err
+		}
+
+		return tx.KizamiRepo.Delete(ki)
+	})
 }
 
 // List returns all Kizamis
@@ -100,26 +381,147 @@ func (k *Kokizami) List() ([]*Kizami, error) {
 	return k.KizamiRepo.FindAll()
 }
 
-// SummaryByTag returns total elapsed time of Kizamis in specified month grouped by tag
-func (k *Kokizami) SummaryByTag(yyyymm string) ([]*Elapsed, error) {
-	// validate input
-	_, err := time.Parse("2006-01", yyyymm)
-	if err != nil {
-		return nil, fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+// Find returns Kizamis that satisfy specified query
+func (k *Kokizami) Find(q KizamiQuery) ([]*Kizami, error) {
+	if !q.Since.IsZero() && !q.Until.IsZero() {
+		if err := validateRange(q.Since, q.Until); err != nil {
+			return nil, err
+		}
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return nil, fmt.Errorf("limit (%d) and offset (%d) must not be negative", q.Limit, q.Offset)
+	}
+	if q.Now.IsZero() {
+		q.Now = k.timeNow()
+	}
+	q.Since = q.Since.UTC()
+	q.Until = q.Until.UTC()
+	q.Now = q.Now.UTC()
+
+	return k.KizamiRepo.Find(&q)
+}
+
+func validateRange(from, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("invalid range. from (%v) must be before to (%v)", from, to)
+	}
+	return nil
+}
+
+// location returns the location to bucket kizamis
+func (k *Kokizami) location() *time.Location {
+	if k.Location == nil {
+		return time.Local
+	}
+	return k.Location
+}
+
+// Range returns the range of specified period that contains t.
+// The range is calculated in Kokizami's location.
+func (k *Kokizami) Range(p Period, t time.Time) (from, to time.Time) {
+	return p.Range(t.In(k.location()))
+}
+
+func (k *Kokizami) summaryOptions(opts []SummaryOption) *SummaryOptions {
+	o := &SummaryOptions{
+		Now: k.timeNow().UTC(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// SummaryByTag returns total elapsed time of Kizamis in specified range grouped by tag.
+// The range is half-open, from is inclusive and to is exclusive.
+func (k *Kokizami) SummaryByTag(from, to time.Time, opts ...SummaryOption) ([]*Elapsed, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	return k.SummaryRepo.ElapsedByTag(from.UTC(), to.UTC(), k.summaryOptions(opts))
+}
+
+// SummaryByDesc returns total elapsed time of Kizamis in specified range grouped by desc.
+// Each kizami is counted only once regardless of attribution, with its primary tag as Tag.
+// The range is half-open, from is inclusive and to is exclusive.
+func (k *Kokizami) SummaryByDesc(from, to time.Time, opts ...SummaryOption) ([]*Elapsed, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	return k.SummaryRepo.ElapsedByDesc(from.UTC(), to.UTC(), k.summaryOptions(opts))
+}
+
+// SummaryTotal returns total elapsed time of Kizamis in specified range.
+// Each kizami is counted only once regardless of its tags.
+func (k *Kokizami) SummaryTotal(from, to time.Time, opts ...SummaryOption) (*Elapsed, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	return k.SummaryRepo.ElapsedTotal(from.UTC(), to.UTC(), k.summaryOptions(opts))
+}
+
+// Periods splits the range [from, to) into periods in Kokizami's location.
+// The first and the last periods are clipped to the range.
+func (k *Kokizami) Periods(from, to time.Time, p Period) ([][2]time.Time, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
 	}
 
-	return k.SummaryRepo.ElapsedOfMonthByTag(yyyymm)
+	ret := [][2]time.Time{}
+	for cur := from; cur.Before(to); {
+		pf, pt := k.Range(p, cur)
+		next := pt
+		if pf.Before(from) {
+			pf = from
+		}
+		if pt.After(to) {
+			pt = to
+		}
+		ret = append(ret, [2]time.Time{pf, pt})
+		cur = next
+	}
+
+	return ret, nil
 }
 
-// SummaryByDesc returns total elapsed time of Kizamis in specified month grouped by desc
-func (k *Kokizami) SummaryByDesc(yyyymm string) ([]*Elapsed, error) {
-	// validate input
-	_, err := time.Parse("2006-01", yyyymm)
+func (k *Kokizami) summaryPerPeriod(from, to time.Time, p Period, opts []SummaryOption,
+	summary func(from, to time.Time, opts ...SummaryOption) ([]*Elapsed, error)) ([]*PeriodSummary, error) {
+	ps, err := k.Periods(from, to, p)
 	if err != nil {
-		return nil, fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+		return nil, err
 	}
 
-	return k.SummaryRepo.ElapsedOfMonthByDesc(yyyymm)
+	ret := make([]*PeriodSummary, len(ps))
+	for i := range ps {
+		// kizamis that straddle the period boundaries are apportioned
+		// to each period since summary clips them to the range
+		es, err := summary(ps[i][0], ps[i][1], opts...)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = &PeriodSummary{
+			From:    ps[i][0],
+			To:      ps[i][1],
+			Elapsed: es,
+		}
+	}
+
+	return ret, nil
+}
+
+// SummaryByTagPerPeriod returns total elapsed time of Kizamis grouped by tag
+// for each period in specified range
+func (k *Kokizami) SummaryByTagPerPeriod(from, to time.Time, p Period, opts ...SummaryOption) ([]*PeriodSummary, error) {
+	return k.summaryPerPeriod(from, to, p, opts, k.SummaryByTag)
+}
+
+// SummaryByDescPerPeriod returns total elapsed time of Kizamis grouped by desc
+// for each period in specified range
+func (k *Kokizami) SummaryByDescPerPeriod(from, to time.Time, p Period, opts ...SummaryOption) ([]*PeriodSummary, error) {
+	return k.summaryPerPeriod(from, to, p, opts, k.SummaryByDesc)
 }
 
 // AddTags adds a new tags
@@ -132,6 +534,124 @@ func (k *Kokizami) DeleteTag(id int) error {
 	return k.TagRepo.Delete(id)
 }
 
+// RenameTag renames a tag labeled from to to.
+// Descs of kizamis tagged with from are rewritten. If a tag labeled to
+// exists already, the tag labeled from is merged into it.
+func (k *Kokizami) RenameTag(from, to string) error {
+	return k.MergeTags([]string{from}, to)
+}
+
+// MergeTags merges tags labeled from into a tag labeled into.
+// Descs of kizamis tagged with from are rewritten and tags labeled from are deleted.
+// All tags are left as they were if any of them is failed.
+func (k *Kokizami) MergeTags(from []string, into string) error {
+	if len(from) == 0 {
+		return fmt.Errorf("specify tags to merge")
+	}
+	for _, v := range from {
+		if err := validateLabel(v); err != nil {
+			return err
+		}
+	}
+	if err := validateLabel(into); err != nil {
+		return err
+	}
+
+	return k.Transaction(func(tx *Kokizami) error {
+		for _, v := range from {
+			if v == into {
+				continue
+			}
+			err := tx.rewriteTag(v, into)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveTag removes a tag labeled label.
+// "#" of the tag is removed from descs of kizamis tagged with it,
+// so that the word remains in descs as plain text.
+func (k *Kokizami) RemoveTag(label string) error {
+	if err := validateLabel(label); err != nil {
+		return err
+	}
+
+	return k.Transaction(func(tx *Kokizami) error {
+		return tx.rewriteTag(label, strings.TrimPrefix(label, "#"))
+	})
+}
+
+// rewriteTag rewrites descs of kizamis tagged with label to replace it with to,
+// retags the kizamis and deletes the tag labeled label
+func (k *Kokizami) rewriteTag(label, to string) error {
+	ts, err := k.TagRepo.FindByLabels([]string{label})
+	if err != nil {
+		return err
+	}
+	if len(ts) == 0 {
+		return fmt.Errorf("tag [%s] is not found", label)
+	}
+
+	ks, err := k.KizamiRepo.Find(&KizamiQuery{Tags: []string{label}})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range ks {
+		desc := rewriteTag(v.Desc, []string{label}, to)
+		if desc == v.Desc {
+			// descendants of the tag are not changed
+			continue
+		}
+		v.Desc = desc
+		err = k.KizamiRepo.Update(v)
+		if err != nil {
+			return err
+		}
+
+		err = k.retag(v.ID, v.Desc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return k.TagRepo.Delete(ts[0].ID)
+}
+
+// TagUsages returns how much each tag is used
+func (k *Kokizami) TagUsages() ([]*TagUsage, error) {
+	ts, err := k.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	// summarize all kizamis including on-going ones
+	from := initialTime()
+	to := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	es, err := k.SummaryRepo.ElapsedByTag(from, to, k.summaryOptions([]SummaryOption{IncludeRunning()}))
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]*Elapsed{}
+	for _, v := range es {
+		m[v.Tag] = v
+	}
+
+	ret := make([]*TagUsage, len(ts))
+	for i, v := range ts {
+		ret[i] = &TagUsage{Tag: *v}
+		if e, ok := m[v.Label]; ok {
+			ret[i].Count = e.Count
+			ret[i].Elapsed = e.Elapsed
+		}
+	}
+
+	return ret, nil
+}
+
 // Tags returns list of tags
 func (k *Kokizami) Tags() ([]*Tag, error) {
 	ms, err := k.TagRepo.FindAll()
@@ -184,6 +704,12 @@ func (k *Kokizami) TagsByKizamiID(kizamiID int) ([]*Tag, error) {
 	return ret, nil
 }
 
+// TagsByKizamiIDs returns tags of each of specified kizamis at once.
+// Kizamis that have no tag are not contained in the result.
+func (k *Kokizami) TagsByKizamiIDs(kizamiIDs []int) (map[int][]*Tag, error) {
+	return k.TagRepo.FindByKizamiIDs(kizamiIDs)
+}
+
 // TagsByLabels returns tags by specified tags
 func (k *Kokizami) TagsByLabels(labels []string) ([]*Tag, error) {
 	return k.TagRepo.FindByLabels(labels)
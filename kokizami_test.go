@@ -2,7 +2,9 @@ package kokizami
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +26,20 @@ type mockTagRepo struct {
 	repo *mockRepo
 }
 
-type mockSummaryRepo struct{}
+type mockSummaryRepo struct {
+	repo *mockRepo
+}
+
+type mockTxRepo struct {
+	now  func() time.Time
+	repo *mockRepo
+}
+
+type mockTransaction struct {
+	now      func() time.Time
+	repo     *mockRepo
+	snapshot mockRepo
+}
 
 func (m *mockKizamiRepo) FindAll() ([]*Kizami, error) {
 	ks := make([]Kizami, len(m.repo.kizamis))
@@ -54,6 +69,18 @@ func (m *mockKizamiRepo) Insert(desc string) (*Kizami, error) {
 	return k, nil
 }
 
+func (m *mockKizamiRepo) InsertWithTime(desc string, startedAt, stoppedAt time.Time) (*Kizami, error) {
+	id := len(m.repo.kizamis) + 1
+	k := &Kizami{
+		ID:        id,
+		Desc:      desc,
+		StartedAt: startedAt,
+		StoppedAt: stoppedAt,
+	}
+	m.repo.kizamis[strconv.Itoa(id)] = k
+	return k, nil
+}
+
 func (m *mockKizamiRepo) Update(k *Kizami) error {
 	m.repo.kizamis[strconv.Itoa(k.ID)] = k
 	return nil
@@ -81,6 +108,24 @@ func (m *mockKizamiRepo) FindByStoppedAt(t time.Time) ([]*Kizami, error) {
 	return ret, nil
 }
 
+func (m *mockKizamiRepo) FindOverlapped(startedAt, stoppedAt time.Time) ([]*Kizami, error) {
+	ret := []*Kizami{}
+	for k, v := range m.repo.kizamis {
+		if v.StoppedAt.Unix() != 0 && !v.StoppedAt.After(startedAt) {
+			continue
+		}
+		if stoppedAt.Unix() != 0 && !v.StartedAt.Before(stoppedAt) {
+			continue
+		}
+		ret = append(ret, m.repo.kizamis[k])
+	}
+	return ret, nil
+}
+
+func (m *mockKizamiRepo) Find(q *KizamiQuery) ([]*Kizami, error) {
+	panic("not implemented")
+}
+
 func (m *mockKizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
 	m.repo.relation[kizamiID] = tagIDs
 	return nil
@@ -91,6 +136,44 @@ func (m *mockKizamiRepo) Untagging(kizamiID int) error {
 	return nil
 }
 
+func (m *mockKizamiRepo) InsertInterval(i *Interval) error {
+	k, err := m.FindByID(i.KizamiID)
+	if err != nil {
+		return err
+	}
+	i.ID = len(k.Intervals) + 1
+	k.Intervals = append(k.Intervals, *i)
+	return nil
+}
+
+func (m *mockKizamiRepo) UpdateInterval(i *Interval) error {
+	k, err := m.FindByID(i.KizamiID)
+	if err != nil {
+		return err
+	}
+	for j := range k.Intervals {
+		if k.Intervals[j].ID == i.ID {
+			k.Intervals[j] = *i
+			return nil
+		}
+	}
+	return fmt.Errorf("Interval that has id [%d] is not found", i.ID)
+}
+
+func (m *mockKizamiRepo) DeleteInterval(i *Interval) error {
+	k, err := m.FindByID(i.KizamiID)
+	if err != nil {
+		return err
+	}
+	for j := range k.Intervals {
+		if k.Intervals[j].ID == i.ID {
+			k.Intervals = append(k.Intervals[:j], k.Intervals[j+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Interval that has id [%d] is not found", i.ID)
+}
+
 func (m *mockTagRepo) FindByID(id int) (*Tag, error) {
 	panic("not implemented")
 }
@@ -123,6 +206,20 @@ func (m *mockTagRepo) FindByKizamiID(kizamiID int) ([]*Tag, error) {
 	return ret, nil
 }
 
+func (m *mockTagRepo) FindByKizamiIDs(kizamiIDs []int) (map[int][]*Tag, error) {
+	ret := map[int][]*Tag{}
+	for _, id := range kizamiIDs {
+		ts, err := m.FindByKizamiID(id)
+		if err != nil {
+			return nil, err
+		}
+		if len(ts) > 0 {
+			ret[id] = ts
+		}
+	}
+	return ret, nil
+}
+
 func (m *mockTagRepo) FindByLabels(labels []string) ([]*Tag, error) {
 	ret := []*Tag{}
 	for _, label := range labels {
@@ -138,6 +235,10 @@ func (m *mockTagRepo) FindByLabels(labels []string) ([]*Tag, error) {
 
 func (m *mockTagRepo) Insert(labels []string) error {
 	for i := range labels {
+		if ts, _ := m.FindByLabels(labels[i : i+1]); len(ts) > 0 {
+			// label is unique
+			continue
+		}
 		id := len(m.repo.tags) + 1
 		t := &Tag{
 			ID:    id,
@@ -154,12 +255,178 @@ func (m *mockTagRepo) Delete(id int) error {
 	return nil
 }
 
-func (m *mockSummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*Elapsed, error) {
-	return nil, nil
+func (m *mockSummaryRepo) elapsedBy(from, to time.Time, opts *SummaryOptions, byDesc bool) ([]*Elapsed, error) {
+	es := map[[2]string]*Elapsed{}
+	keys := [][2]string{}
+	for _, k := range m.repo.kizamis {
+		spans := k.Intervals
+		if len(spans) == 0 {
+			spans = []Interval{{StartedAt: k.StartedAt, StoppedAt: k.StoppedAt}}
+		}
+
+		var (
+			elapsed time.Duration
+			running bool
+			found   bool
+		)
+		for _, span := range spans {
+			start, stop := span.StartedAt, span.StoppedAt
+			if stop.Unix() == 0 {
+				if !opts.IncludeRunning {
+					continue
+				}
+				stop = opts.Now
+				running = true
+			}
+
+			if start.Before(from) {
+				start = from
+			}
+			if stop.After(to) {
+				stop = to
+			}
+			if !start.Before(stop) {
+				continue
+			}
+			elapsed += stop.Sub(start)
+			found = true
+		}
+		if !found {
+			continue
+		}
+
+		labels := []string{""}
+		if tids := m.repo.relation[k.ID]; len(tids) > 0 {
+			labels = labels[:0]
+			for _, tid := range tids {
+				labels = append(labels, m.repo.tags[strconv.Itoa(tid)].Label)
+			}
+		}
+
+		switch opts.Attribution {
+		case AttributionSplit:
+			elapsed /= time.Duration(len(labels))
+		case AttributionPrimary:
+			sort.Slice(labels, func(i, j int) bool {
+				return strings.Index(" "+k.Desc+" ", " "+labels[i]+" ") <
+					strings.Index(" "+k.Desc+" ", " "+labels[j]+" ")
+			})
+			labels = labels[:1]
+		}
+
+		for _, label := range labels {
+			key := [2]string{label, ""}
+			if byDesc {
+				key[1] = k.Desc
+			}
+			e, ok := es[key]
+			if !ok {
+				e = &Elapsed{Tag: key[0], Desc: key[1]}
+				es[key] = e
+				keys = append(keys, key)
+			}
+			e.Count++
+			e.Elapsed += elapsed
+			e.InProgress = e.InProgress || running
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	ret := make([]*Elapsed, len(keys))
+	for i := range keys {
+		ret[i] = es[keys[i]]
+	}
+
+	return ret, nil
+}
+
+func (m *mockSummaryRepo) ElapsedByDesc(from, to time.Time, opts *SummaryOptions) ([]*Elapsed, error) {
+	// each desc is counted once with its primary tag
+	byDesc := *opts
+	byDesc.Attribution = AttributionPrimary
+	return m.elapsedBy(from, to, &byDesc, true)
+}
+
+func (m *mockSummaryRepo) ElapsedByTag(from, to time.Time, opts *SummaryOptions) ([]*Elapsed, error) {
+	return m.elapsedBy(from, to, opts, false)
+}
+
+func (m *mockSummaryRepo) ElapsedTotal(from, to time.Time, opts *SummaryOptions) (*Elapsed, error) {
+	total := &Elapsed{}
+	for _, k := range m.repo.kizamis {
+		es, err := (&mockSummaryRepo{
+			repo: &mockRepo{kizamis: map[string]*Kizami{"": k}},
+		}).elapsedBy(from, to, opts, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range es {
+			total.Count += e.Count
+			total.Elapsed += e.Elapsed
+			total.InProgress = total.InProgress || e.InProgress
+		}
+	}
+	return total, nil
+}
+
+func (m *mockTxRepo) Begin() (Transaction, error) {
+	t := &mockTransaction{
+		now:  m.now,
+		repo: m.repo,
+		snapshot: mockRepo{
+			kizamis:  map[string]*Kizami{},
+			relation: map[int][]int{},
+			tags:     map[string]*Tag{},
+		},
+	}
+	for k, v := range m.repo.kizamis {
+		ki := *v
+		ki.Intervals = append([]Interval{}, v.Intervals...)
+		t.snapshot.kizamis[k] = &ki
+	}
+	for k, v := range m.repo.relation {
+		t.snapshot.relation[k] = append([]int{}, v...)
+	}
+	for k, v := range m.repo.tags {
+		tag := *v
+		t.snapshot.tags[k] = &tag
+	}
+	return t, nil
+}
+
+func (m *mockTransaction) KizamiRepo() KizamiRepository {
+	return &mockKizamiRepo{now: m.now, repo: m.repo}
+}
+
+func (m *mockTransaction) TagRepo() TagRepository {
+	return &mockTagRepo{repo: m.repo}
+}
+
+func (m *mockTransaction) SummaryRepo() SummaryRepository {
+	return &mockSummaryRepo{repo: m.repo}
+}
+
+func (m *mockTransaction) SearchRepo() SearchRepository {
+	return nil
+}
+
+func (m *mockTransaction) EventRepo() EventRepository {
+	return nil
+}
+
+func (m *mockTransaction) Commit() error {
+	return nil
 }
 
-func (m *mockSummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*Elapsed, error) {
-	return nil, nil
+func (m *mockTransaction) Rollback() error {
+	*m.repo = m.snapshot
+	return nil
 }
 
 func setup() *Kokizami {
@@ -170,7 +437,7 @@ func setup() *Kokizami {
 		tags:     map[string]*Tag{},
 	}
 	return &Kokizami{
-		now: func() time.Time { return mockNow },
+		Now: func() time.Time { return mockNow },
 
 		KizamiRepo: &mockKizamiRepo{
 			now:  func() time.Time { return mockNow },
@@ -179,7 +446,13 @@ func setup() *Kokizami {
 		TagRepo: &mockTagRepo{
 			repo: repo,
 		},
-		SummaryRepo: &mockSummaryRepo{},
+		SummaryRepo: &mockSummaryRepo{
+			repo: repo,
+		},
+		TxRepo: &mockTxRepo{
+			now:  func() time.Time { return mockNow },
+			repo: repo,
+		},
 	}
 }
 
@@ -202,7 +475,7 @@ func TestStart(t *testing.T) {
 			wantKizami: &Kizami{
 				ID:        1,
 				Desc:      "hoge",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -224,6 +497,69 @@ func TestStart(t *testing.T) {
 	}
 }
 
+func TestAdd(t *testing.T) {
+	k := setup()
+
+	startedAt := time.Date(2019, 5, 1, 9, 30, 0, 0, time.UTC)
+	stoppedAt := time.Date(2019, 5, 1, 10, 15, 0, 0, time.UTC)
+
+	tcs := []struct {
+		inDesc      string
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		wantErr     bool
+		wantKizami  *Kizami
+	}{
+		{
+			inDesc:      "",
+			inStartedAt: startedAt,
+			inStoppedAt: stoppedAt,
+			wantErr:     true,
+		},
+		{
+			inDesc:      "hoge",
+			inStartedAt: stoppedAt,
+			inStoppedAt: startedAt,
+			wantErr:     true,
+		},
+		{
+			inDesc:      "hoge",
+			inStartedAt: startedAt,
+			inStoppedAt: startedAt,
+			wantErr:     true,
+		},
+		{
+			inDesc:      "hoge",
+			inStartedAt: startedAt,
+			inStoppedAt: stoppedAt,
+			wantErr:     false,
+			wantKizami: &Kizami{
+				ID:        1,
+				Desc:      "hoge",
+				StartedAt: startedAt,
+				StoppedAt: stoppedAt,
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := k.Add(tc.inDesc, tc.inStartedAt, tc.inStoppedAt)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		if diff := cmp.Diff(ret, tc.wantKizami); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
 func TestGet(t *testing.T) {
 	k := setup()
 
@@ -238,7 +574,7 @@ func TestGet(t *testing.T) {
 			wantKizami: &Kizami{
 				ID:        1,
 				Desc:      "hoge",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -248,7 +584,7 @@ func TestGet(t *testing.T) {
 			wantKizami: &Kizami{
 				ID:        2,
 				Desc:      "fuga",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -258,7 +594,7 @@ func TestGet(t *testing.T) {
 			wantKizami: &Kizami{
 				ID:        3,
 				Desc:      "piyo",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -295,7 +631,7 @@ func TestEdit(t *testing.T) {
 			inDesc: "hoge",
 			wantKizami: &Kizami{
 				Desc:      "edited hoge",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -303,7 +639,7 @@ func TestEdit(t *testing.T) {
 			inDesc: "fuga",
 			wantKizami: &Kizami{
 				Desc:      "edited fuga",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -311,7 +647,7 @@ func TestEdit(t *testing.T) {
 			inDesc: "piyo",
 			wantKizami: &Kizami{
 				Desc:      "edited piyo",
-				StartedAt: k.now(),
+				StartedAt: k.Now(),
 				StoppedAt: initialTime(),
 			},
 		},
@@ -351,24 +687,24 @@ func TestStop(t *testing.T) {
 			inDesc: "hoge",
 			wantKizami: &Kizami{
 				Desc:      "hoge",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 		{
 			inDesc: "fuga",
 			wantKizami: &Kizami{
 				Desc:      "fuga",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 		{
 			inDesc: "piyo",
 			wantKizami: &Kizami{
 				Desc:      "piyo",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 	}
@@ -396,6 +732,36 @@ func TestStop(t *testing.T) {
 	}
 }
 
+func TestStartAtAndStopAt(t *testing.T) {
+	k := setup()
+
+	startedAt := time.Date(2019, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	ki, err := k.StartAt("hoge", startedAt)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = k.StopAt(ki.ID, startedAt.Add(-time.Minute))
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	err = k.StopAt(ki.ID, startedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	if ret.Elapsed() != time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.Elapsed(), time.Hour)
+	}
+}
+
 func TestStopAll(t *testing.T) {
 	k := setup()
 
@@ -407,24 +773,24 @@ func TestStopAll(t *testing.T) {
 			inDesc: "hoge",
 			wantKizami: &Kizami{
 				Desc:      "hoge",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 		{
 			inDesc: "fuga",
 			wantKizami: &Kizami{
 				Desc:      "fuga",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 		{
 			inDesc: "piyo",
 			wantKizami: &Kizami{
 				Desc:      "piyo",
-				StartedAt: k.now(),
-				StoppedAt: k.now(),
+				StartedAt: k.Now(),
+				StoppedAt: k.Now(),
 			},
 		},
 	}
@@ -632,17 +998,639 @@ func TestTagging(t *testing.T) {
 func TestSummaryByTag(t *testing.T) {
 	k := setup()
 
-	_, err := k.SummaryByTag("2019-05")
+	from := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := k.SummaryByTag(from, to)
 	if err != nil {
 		t.Fatalf("unexpected result: [got] %v [want] nil", err)
 	}
+
+	_, err = k.SummaryByTag(to, from)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
 }
 
 func TestSummaryByDesc(t *testing.T) {
 	k := setup()
 
-	_, err := k.SummaryByDesc("2019-05")
+	from := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := k.SummaryByDesc(from, to)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	_, err = k.SummaryByDesc(to, from)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestPeriodRange(t *testing.T) {
+	tcs := []struct {
+		inPeriod Period
+		inTime   time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			inPeriod: PeriodDay,
+			inTime:   time.Date(2019, 1, 31, 23, 0, 0, 0, time.UTC),
+			wantFrom: time.Date(2019, 1, 31, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// 2019-01-02 is Wednesday
+			inPeriod: PeriodWeek,
+			inTime:   time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC),
+			wantFrom: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2019, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// 2019-01-06 is Sunday
+			inPeriod: PeriodWeek,
+			inTime:   time.Date(2019, 1, 6, 12, 0, 0, 0, time.UTC),
+			wantFrom: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2019, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			inPeriod: PeriodMonth,
+			inTime:   time.Date(2019, 12, 15, 12, 0, 0, 0, time.UTC),
+			wantFrom: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for i, tc := range tcs {
+		from, to := tc.inPeriod.Range(tc.inTime)
+		if !from.Equal(tc.wantFrom) || !to.Equal(tc.wantTo) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v - %v [want] %v - %v", i, from, to, tc.wantFrom, tc.wantTo)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tcs := []struct {
+		inLocation *time.Location
+		inTime     time.Time
+		wantFrom   time.Time
+		wantTo     time.Time
+	}{
+		{
+			// 2019-06-01 08:00 in JST is still May in UTC
+			inLocation: jst,
+			inTime:     time.Date(2019, 5, 31, 23, 0, 0, 0, time.UTC),
+			wantFrom:   time.Date(2019, 6, 1, 0, 0, 0, 0, jst),
+			wantTo:     time.Date(2019, 7, 1, 0, 0, 0, 0, jst),
+		},
+		{
+			inLocation: time.UTC,
+			inTime:     time.Date(2019, 5, 31, 23, 0, 0, 0, time.UTC),
+			wantFrom:   time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:     time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for i, tc := range tcs {
+		k := setup()
+		k.Location = tc.inLocation
+
+		from, to := k.Range(PeriodMonth, tc.inTime)
+		if !from.Equal(tc.wantFrom) || !to.Equal(tc.wantTo) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v - %v [want] %v - %v", i, from, to, tc.wantFrom, tc.wantTo)
+		}
+	}
+}
+
+func TestSummaryIncludeRunning(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki.StartedAt = k.Now().Add(-2 * time.Hour)
+	_, err = k.Edit(ki)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	from := k.Now().Add(-24 * time.Hour)
+	to := k.Now().Add(24 * time.Hour)
+
+	ret, err := k.SummaryByTag(from, to)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ret) != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ret), 0)
+	}
+
+	ret, err = k.SummaryByTag(from, to, IncludeRunning())
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := []*Elapsed{
+		{
+			Count:      1,
+			Elapsed:    2 * time.Hour,
+			InProgress: true,
+		},
+	}
+	if diff := cmp.Diff(ret, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestSummaryAttribution(t *testing.T) {
+	k := setup()
+
+	from := k.Now().Add(-24 * time.Hour)
+	to := k.Now().Add(24 * time.Hour)
+
+	err := k.AddTags([]string{"#a", "#b"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	for _, desc := range []string{"hoge #b #a", "fuga #b"} {
+		ki, err := k.Start(desc)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		ki.StoppedAt = ki.StartedAt.Add(time.Hour)
+		_, err = k.Edit(ki)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	tcs := []struct {
+		inAttribution Attribution
+		wantElapsed   map[string]time.Duration
+	}{
+		{
+			inAttribution: AttributionFull,
+			wantElapsed:   map[string]time.Duration{"#a": time.Hour, "#b": 2 * time.Hour},
+		},
+		{
+			inAttribution: AttributionSplit,
+			wantElapsed:   map[string]time.Duration{"#a": 30 * time.Minute, "#b": 90 * time.Minute},
+		},
+		{
+			inAttribution: AttributionPrimary,
+			wantElapsed:   map[string]time.Duration{"#b": 2 * time.Hour},
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := k.SummaryByTag(from, to, WithAttribution(tc.inAttribution))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		got := map[string]time.Duration{}
+		for _, e := range ret {
+			got[e.Tag] = e.Elapsed
+		}
+		if diff := cmp.Diff(got, tc.wantElapsed); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+
+		total, err := k.SummaryTotal(from, to, WithAttribution(tc.inAttribution))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if total.Elapsed != 2*time.Hour || total.Count != 2 {
+			t.Fatalf("[No.%d] unexpected result: [got] %v, %v [want] %v, %v", i, total.Elapsed, total.Count, 2*time.Hour, 2)
+		}
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	k := setup()
+
+	now := k.Now()
+	k.Now = func() time.Time { return now }
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki.StartedAt = now.Add(-3 * time.Hour)
+	_, err = k.Edit(ki)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = k.Resume(ki.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	// work 1h, pause 1h, then work 1h
+	now = now.Add(-2 * time.Hour)
+	err = k.Pause(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = k.Pause(ki.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	now = now.Add(time.Hour)
+	err = k.Resume(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now = now.Add(time.Hour)
+	err = k.Stop(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ret.Intervals) != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ret.Intervals), 2)
+	}
+	if ret.Elapsed() != 2*time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.Elapsed(), 2*time.Hour)
+	}
+
+	total, err := k.SummaryTotal(now.Add(-24*time.Hour), now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if total.Elapsed != 2*time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", total.Elapsed, 2*time.Hour)
+	}
+}
+
+func TestEditPausedKizami(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+	}
+	type term [2]time.Time
+
+	// kizami from 09:00 to 12:00 that is paused from 10:00 to 11:00
+	tcs := []struct {
+		inStartedAt   time.Time
+		inStoppedAt   time.Time
+		wantIntervals []term
+		wantElapsed   time.Duration
+	}{
+		{
+			inStartedAt:   at(9, 0),
+			inStoppedAt:   at(13, 0),
+			wantIntervals: []term{{at(9, 0), at(10, 0)}, {at(11, 0), at(13, 0)}},
+			wantElapsed:   3 * time.Hour,
+		},
+		{
+			inStartedAt:   at(8, 0),
+			inStoppedAt:   at(12, 0),
+			wantIntervals: []term{{at(8, 0), at(10, 0)}, {at(11, 0), at(12, 0)}},
+			wantElapsed:   3 * time.Hour,
+		},
+		{
+			inStartedAt:   at(9, 30),
+			inStoppedAt:   at(11, 30),
+			wantIntervals: []term{{at(9, 30), at(10, 0)}, {at(11, 0), at(11, 30)}},
+			wantElapsed:   time.Hour,
+		},
+		{
+			inStartedAt:   at(10, 30),
+			inStoppedAt:   at(12, 0),
+			wantIntervals: []term{{at(11, 0), at(12, 0)}},
+			wantElapsed:   time.Hour,
+		},
+		{
+			inStartedAt:   at(9, 0),
+			inStoppedAt:   at(10, 30),
+			wantIntervals: []term{{at(9, 0), at(10, 0)}},
+			wantElapsed:   time.Hour,
+		},
+	}
+
+	for i, tc := range tcs {
+		k := setup()
+
+		ki, err := k.Add("hoge", at(9, 0), at(12, 0))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		for _, v := range []term{{at(9, 0), at(10, 0)}, {at(11, 0), at(12, 0)}} {
+			err = k.KizamiRepo.InsertInterval(&Interval{KizamiID: ki.ID, StartedAt: v[0], StoppedAt: v[1]})
+			if err != nil {
+				t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+			}
+		}
+
+		ret, err := k.Edit(&Kizami{ID: ki.ID, Desc: "hoge", StartedAt: tc.inStartedAt, StoppedAt: tc.inStoppedAt})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		got := []term{}
+		for _, v := range ret.Intervals {
+			got = append(got, term{v.StartedAt, v.StoppedAt})
+		}
+		if diff := cmp.Diff(got, tc.wantIntervals); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if ret.Elapsed() != tc.wantElapsed {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, ret.Elapsed(), tc.wantElapsed)
+		}
+
+		total, err := k.SummaryTotal(at(0, 0), at(24, 0))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if total.Elapsed != tc.wantElapsed {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, total.Elapsed, tc.wantElapsed)
+		}
+	}
+}
+
+func TestOverlapPolicy(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+	}
+
+	tcs := []struct {
+		name        string
+		inPolicy    OverlapPolicy
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		wantErr     bool
+		wantWarned  int
+		// want range of existing kizami that is from 10:00 to 11:00
+		wantStartedAt time.Time
+		wantStoppedAt time.Time
+	}{
+		{
+			name:          "no overlap",
+			inPolicy:      OverlapReject,
+			inStartedAt:   at(11, 0),
+			inStoppedAt:   at(12, 0),
+			wantStartedAt: at(10, 0),
+			wantStoppedAt: at(11, 0),
+		},
+		{
+			name:          "warn",
+			inPolicy:      OverlapWarn,
+			inStartedAt:   at(10, 30),
+			inStoppedAt:   at(12, 0),
+			wantWarned:    1,
+			wantStartedAt: at(10, 0),
+			wantStoppedAt: at(11, 0),
+		},
+		{
+			name:          "reject",
+			inPolicy:      OverlapReject,
+			inStartedAt:   at(10, 30),
+			inStoppedAt:   at(12, 0),
+			wantErr:       true,
+			wantStartedAt: at(10, 0),
+			wantStoppedAt: at(11, 0),
+		},
+		{
+			name:          "trim tail",
+			inPolicy:      OverlapTrim,
+			inStartedAt:   at(10, 30),
+			inStoppedAt:   at(12, 0),
+			wantStartedAt: at(10, 0),
+			wantStoppedAt: at(10, 30),
+		},
+		{
+			name:          "trim head",
+			inPolicy:      OverlapTrim,
+			inStartedAt:   at(9, 0),
+			inStoppedAt:   at(10, 15),
+			wantStartedAt: at(10, 15),
+			wantStoppedAt: at(11, 0),
+		},
+		{
+			name:          "trim covered",
+			inPolicy:      OverlapTrim,
+			inStartedAt:   at(9, 0),
+			inStoppedAt:   at(12, 0),
+			wantErr:       true,
+			wantStartedAt: at(10, 0),
+			wantStoppedAt: at(11, 0),
+		},
+	}
+
+	for _, tc := range tcs {
+		k := setup()
+
+		existing, err := k.Add("existing", at(10, 0), at(11, 0))
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+		}
+
+		warned := 0
+		k.OverlapPolicy = tc.inPolicy
+		k.OnOverlap = func(_ *Kizami, overlapped []*Kizami) {
+			warned += len(overlapped)
+		}
+
+		_, err = k.Add("new", tc.inStartedAt, tc.inStoppedAt)
+		if tc.wantErr != (err != nil) {
+			t.Fatalf("[%s] unexpected result: [got] %v [want error] %v", tc.name, err, tc.wantErr)
+		}
+		if warned != tc.wantWarned {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] %v", tc.name, warned, tc.wantWarned)
+		}
+
+		ret, err := k.Get(existing.ID)
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+		}
+		if !ret.StartedAt.Equal(tc.wantStartedAt) || !ret.StoppedAt.Equal(tc.wantStoppedAt) {
+			t.Fatalf("[%s] unexpected result: [got] %v - %v [want] %v - %v",
+				tc.name, ret.StartedAt, ret.StoppedAt, tc.wantStartedAt, tc.wantStoppedAt)
+		}
+	}
+}
+
+func TestOverlapTrimPausedKizami(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+	}
+
+	tcs := []struct {
+		name        string
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		// want elapsed time of existing kizami from 10:00 to 11:00
+		// that is paused from 10:20 to 10:40
+		wantElapsed time.Duration
+	}{
+		{name: "trim tail", inStartedAt: at(10, 30), inStoppedAt: at(12, 0), wantElapsed: 20 * time.Minute},
+		{name: "trim head", inStartedAt: at(9, 0), inStoppedAt: at(10, 10), wantElapsed: 30 * time.Minute},
+		{name: "trim head in pause", inStartedAt: at(9, 0), inStoppedAt: at(10, 30), wantElapsed: 20 * time.Minute},
+	}
+
+	for _, tc := range tcs {
+		k := setup()
+		k.OverlapPolicy = OverlapTrim
+
+		existing, err := k.Add("existing", at(10, 0), at(11, 0))
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+		}
+		for _, v := range [][2]time.Time{{at(10, 0), at(10, 20)}, {at(10, 40), at(11, 0)}} {
+			err = k.KizamiRepo.InsertInterval(&Interval{KizamiID: existing.ID, StartedAt: v[0], StoppedAt: v[1]})
+			if err != nil {
+				t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+			}
+		}
+
+		_, err = k.Add("new", tc.inStartedAt, tc.inStoppedAt)
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+		}
+
+		ret, err := k.Get(existing.ID)
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", tc.name, err)
+		}
+		if ret.Elapsed() != tc.wantElapsed {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] %v", tc.name, ret.Elapsed(), tc.wantElapsed)
+		}
+		for _, v := range ret.Intervals {
+			if v.StartedAt.Before(ret.StartedAt) || v.StoppedAt.After(ret.StoppedAt) {
+				t.Fatalf("[%s] unexpected result: [got] %v [want] in %v - %v", tc.name, v, ret.StartedAt, ret.StoppedAt)
+			}
+		}
+	}
+}
+
+func TestOverlapPolicyOnStart(t *testing.T) {
+	k := setup()
+	k.OverlapPolicy = OverlapTrim
+
+	running, err := k.StartAt("running", k.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	_, err = k.Start("new")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err := k.Get(running.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.StoppedAt.Equal(k.Now()) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.StoppedAt, k.Now())
+	}
+}
+
+func TestOverlapWarnOnStart(t *testing.T) {
+	k := setup()
+
+	warned := 0
+	k.OnOverlap = func(_ *Kizami, overlapped []*Kizami) {
+		warned += len(overlapped)
+	}
+
+	_, err := k.StartAt("running", k.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// starting another kizami while one is running is not warned
+	_, err = k.Start("new")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if warned != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] 0", warned)
+	}
+
+	// but starting one before running ones is
+	_, err = k.StartAt("earlier", k.Now().Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if warned != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] 2", warned)
+	}
+}
+
+func TestEditInvertedRange(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge")
 	if err != nil {
 		t.Fatalf("unexpected result: [got] %v [want] nil", err)
 	}
+
+	ki.StoppedAt = ki.StartedAt.Add(-time.Hour)
+	_, err = k.Edit(ki)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	k := setup()
+
+	at := func(h, m int) time.Time {
+		return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+	}
+
+	for _, r := range [][2]time.Time{
+		{at(9, 0), at(10, 0)},
+		{at(9, 30), at(10, 30)},
+		{at(10, 0), at(11, 0)},
+		{at(12, 0), at(13, 0)},
+	} {
+		_, err := k.Add("hoge", r[0], r[1])
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	// inverted range can be made only by repository directly
+	inverted, err := k.Add("inverted", at(15, 0), at(16, 0))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	inverted.StoppedAt = at(14, 0)
+	err = k.KizamiRepo.Update(inverted)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	cs, err := k.Check()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	got := make([][2]int, len(cs))
+	for i, c := range cs {
+		got[i][0] = c.A.ID
+		if c.B != nil {
+			got[i][1] = c.B.ID
+		}
+	}
+	want := [][2]int{{5, 0}, {1, 2}, {2, 3}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
 }
@@ -0,0 +1,873 @@
+// Package kokizamitest provides a conformance test suite for
+// implementations of repositories of kokizami.
+package kokizamitest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+// Repositories is a set of repositories to be tested
+type Repositories struct {
+	KizamiRepo  kokizami.KizamiRepository
+	TagRepo     kokizami.TagRepository
+	SummaryRepo kokizami.SummaryRepository
+	TxRepo      kokizami.TransactionRepository
+	EventRepo   kokizami.EventRepository
+}
+
+// Setup returns empty repositories and a function to tear down them
+type Setup func(t *testing.T) (*Repositories, func())
+
+// TestRepositories runs conformance tests against repositories returned by setup.
+// setup is called for each test.
+func TestRepositories(t *testing.T, setup Setup) {
+	tcs := []struct {
+		name string
+		test func(t *testing.T, r *Repositories)
+	}{
+		{"Kizami", testKizami},
+		{"FindByStoppedAt", testFindByStoppedAt},
+		{"FindOverlapped", testFindOverlapped},
+		{"Find", testFind},
+		{"Interval", testInterval},
+		{"Tag", testTag},
+		{"Tagging", testTagging},
+		{"Summary", testSummary},
+		{"SummaryClip", testSummaryClip},
+		{"SummaryDepth", testSummaryDepth},
+		{"Transaction", testTransaction},
+		{"Event", testEvent},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r, teardown := setup(t)
+			defer teardown()
+			tc.test(t, r)
+		})
+	}
+}
+
+var base = time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func initialTime() time.Time {
+	return time.Unix(0, 0).UTC()
+}
+
+func at(h, m int) time.Time {
+	return base.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+}
+
+func ids(ks []*kokizami.Kizami) []int {
+	ret := make([]int, len(ks))
+	for i := range ks {
+		ret[i] = ks[i].ID
+	}
+	return ret
+}
+
+func labels(ts []*kokizami.Tag) []string {
+	ret := make([]string, len(ts))
+	for i := range ts {
+		ret[i] = ts[i].Label
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// times are compared by instant since repositories may return them in any location
+var equateTime = cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })
+
+func insert(t *testing.T, r *Repositories, desc string, startedAt, stoppedAt time.Time) *kokizami.Kizami {
+	t.Helper()
+	k, err := r.KizamiRepo.InsertWithTime(desc, startedAt, stoppedAt)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	return k
+}
+
+func tagging(t *testing.T, r *Repositories, k *kokizami.Kizami, ls ...string) {
+	t.Helper()
+	err := r.TagRepo.Insert(ls)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ts, err := r.TagRepo.FindByLabels(ls)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	tids := make([]int, len(ts))
+	for i := range ts {
+		tids[i] = ts[i].ID
+	}
+	err = r.KizamiRepo.Tagging(k.ID, tids)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+}
+
+func testKizami(t *testing.T, r *Repositories) {
+	k1 := insert(t, r, "hoge", at(9, 0), at(10, 0))
+	k2 := insert(t, r, "fuga", at(10, 0), initialTime())
+	if k1.ID == 0 || k1.ID == k2.ID {
+		t.Fatalf("unexpected result: [got] %v, %v [want] unique IDs", k1.ID, k2.ID)
+	}
+
+	k3, err := r.KizamiRepo.Insert("piyo")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if k3.StoppedAt.Unix() != 0 || k3.StartedAt.IsZero() {
+		t.Fatalf("unexpected result: [got] %v [want] a running kizami", k3)
+	}
+
+	got, err := r.KizamiRepo.FindByID(k1.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := &kokizami.Kizami{ID: k1.ID, Desc: "hoge", StartedAt: at(9, 0), StoppedAt: at(10, 0)}
+	if diff := cmp.Diff(got, want, equateTime); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	got.Desc = "hoge2"
+	got.StoppedAt = at(11, 0)
+	err = r.KizamiRepo.Update(got)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ks, err := r.KizamiRepo.FindAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(ids(ks), []int{k1.ID, k2.ID, k3.ID}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	want.Desc = "hoge2"
+	want.StoppedAt = at(11, 0)
+	if diff := cmp.Diff(ks[0], want, equateTime); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = r.KizamiRepo.Delete(k2)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = r.KizamiRepo.FindByID(k2.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	err = r.KizamiRepo.Update(k2)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	err = r.KizamiRepo.Delete(k2)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func testFindByStoppedAt(t *testing.T, r *Repositories) {
+	k1 := insert(t, r, "hoge", at(9, 0), initialTime())
+	_ = insert(t, r, "fuga", at(10, 0), at(11, 0))
+	k3 := insert(t, r, "piyo", at(11, 0), initialTime())
+
+	ks, err := r.KizamiRepo.FindByStoppedAt(initialTime())
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(ids(ks), []int{k1.ID, k3.ID}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func testFindOverlapped(t *testing.T, r *Repositories) {
+	k1 := insert(t, r, "hoge", at(9, 0), at(10, 0))
+	k2 := insert(t, r, "fuga", at(12, 0), initialTime())
+	k3 := insert(t, r, "piyo", at(8, 0), at(9, 0))
+
+	tcs := []struct {
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		want        []int
+	}{
+		{inStartedAt: at(9, 30), inStoppedAt: at(9, 45), want: []int{k1.ID}},
+		{inStartedAt: at(8, 30), inStoppedAt: at(9, 30), want: []int{k3.ID, k1.ID}},
+		// adjacent kizamis do not overlap
+		{inStartedAt: at(10, 0), inStoppedAt: at(12, 0), want: []int{}},
+		// running kizamis continue forever
+		{inStartedAt: at(13, 0), inStoppedAt: at(14, 0), want: []int{k2.ID}},
+		// open ended range continues forever
+		{inStartedAt: at(9, 30), inStoppedAt: initialTime(), want: []int{k1.ID, k2.ID}},
+	}
+
+	for i, tc := range tcs {
+		ks, err := r.KizamiRepo.FindOverlapped(tc.inStartedAt, tc.inStoppedAt)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(ids(ks), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func testFind(t *testing.T, r *Repositories) {
+	k1 := insert(t, r, "write #doc", at(9, 0), at(10, 0))
+	k2 := insert(t, r, "Review #code", at(10, 0), at(10, 30))
+	k3 := insert(t, r, "write #code 100%", at(11, 0), initialTime())
+	k4 := insert(t, r, "lunch", at(12, 0), at(15, 0))
+	tagging(t, r, k1, "#doc")
+	tagging(t, r, k2, "#code")
+	tagging(t, r, k3, "#code")
+
+	err := r.KizamiRepo.InsertInterval(&kokizami.Interval{KizamiID: k3.ID, StartedAt: at(11, 0), StoppedAt: at(11, 10)})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = r.KizamiRepo.InsertInterval(&kokizami.Interval{KizamiID: k3.ID, StartedAt: at(12, 50), StoppedAt: initialTime()})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now := at(13, 0)
+	tcs := []struct {
+		in   kokizami.KizamiQuery
+		want []int
+	}{
+		{in: kokizami.KizamiQuery{}, want: []int{k1.ID, k2.ID, k3.ID, k4.ID}},
+		// kizamis stopped at since are excluded
+		{in: kokizami.KizamiQuery{Since: at(10, 0)}, want: []int{k2.ID, k3.ID, k4.ID}},
+		// running kizamis continue forever
+		{in: kokizami.KizamiQuery{Since: at(16, 0)}, want: []int{k3.ID}},
+		{in: kokizami.KizamiQuery{Until: at(11, 0)}, want: []int{k1.ID, k2.ID}},
+		{in: kokizami.KizamiQuery{Since: at(10, 15), Until: at(11, 30)}, want: []int{k2.ID, k3.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#code"}}, want: []int{k2.ID, k3.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#code", "#doc"}}, want: []int{}},
+		{in: kokizami.KizamiQuery{ExcludeTags: []string{"#code"}}, want: []int{k1.ID, k4.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#code"}, ExcludeTags: []string{"#doc"}}, want: []int{k2.ID, k3.ID}},
+		// desc is matched case-insensitively
+		{in: kokizami.KizamiQuery{Desc: "WRITE"}, want: []int{k1.ID, k3.ID}},
+		// wildcards are matched literally
+		{in: kokizami.KizamiQuery{Desc: "0%"}, want: []int{k3.ID}},
+		{in: kokizami.KizamiQuery{Desc: "_"}, want: []int{}},
+		{in: kokizami.KizamiQuery{Running: true}, want: []int{k3.ID}},
+		{in: kokizami.KizamiQuery{Descending: true}, want: []int{k4.ID, k3.ID, k2.ID, k1.ID}},
+		{in: kokizami.KizamiQuery{OrderBy: kokizami.OrderByStartedAt, Descending: true}, want: []int{k4.ID, k3.ID, k2.ID, k1.ID}},
+		// running kizamis are treated as stopped at now
+		{in: kokizami.KizamiQuery{OrderBy: kokizami.OrderByStoppedAt, Now: now}, want: []int{k1.ID, k2.ID, k3.ID, k4.ID}},
+		// paused terms are not counted
+		{in: kokizami.KizamiQuery{OrderBy: kokizami.OrderByElapsed, Now: now}, want: []int{k3.ID, k2.ID, k1.ID, k4.ID}},
+		{in: kokizami.KizamiQuery{OrderBy: kokizami.OrderByDesc}, want: []int{k2.ID, k4.ID, k3.ID, k1.ID}},
+		{in: kokizami.KizamiQuery{Limit: 2}, want: []int{k1.ID, k2.ID}},
+		{in: kokizami.KizamiQuery{Limit: 2, Offset: 3}, want: []int{k4.ID}},
+		{in: kokizami.KizamiQuery{Offset: 1}, want: []int{k2.ID, k3.ID, k4.ID}},
+		{in: kokizami.KizamiQuery{Offset: 4}, want: []int{}},
+	}
+
+	for i, tc := range tcs {
+		q := tc.in
+		ks, err := r.KizamiRepo.Find(&q)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(ids(ks), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	ks, err := r.KizamiRepo.Find(&kokizami.KizamiQuery{Running: true})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 1 || len(ks[0].Intervals) != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] a kizami with 2 intervals", ks)
+	}
+
+	// intervals are loaded for each kizami
+	ks, err = r.KizamiRepo.Find(&kokizami.KizamiQuery{})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	for _, v := range ks {
+		want := 0
+		if v.ID == k3.ID {
+			want = 2
+		}
+		if len(v.Intervals) != want {
+			t.Fatalf("unexpected result: [got] %v [want] %d intervals", v, want)
+		}
+	}
+
+	// a tag matches its descendants
+	k5 := insert(t, r, "#code/go", at(16, 0), at(17, 0))
+	tagging(t, r, k5, "#code/go")
+	k6 := insert(t, r, "#codegen", at(17, 0), at(18, 0))
+	tagging(t, r, k6, "#codegen")
+	// tags are matched case-sensitively and wildcards are matched literally
+	k7 := insert(t, r, "#Code/go", at(18, 0), at(19, 0))
+	tagging(t, r, k7, "#Code/go")
+	k8 := insert(t, r, "#c?d*/go", at(19, 0), at(20, 0))
+	tagging(t, r, k8, "#c?d*/go")
+	tcs = []struct {
+		in   kokizami.KizamiQuery
+		want []int
+	}{
+		{in: kokizami.KizamiQuery{Tags: []string{"#code"}}, want: []int{k2.ID, k3.ID, k5.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#code/go"}}, want: []int{k5.ID}},
+		{in: kokizami.KizamiQuery{ExcludeTags: []string{"#doc", "#code"}}, want: []int{k4.ID, k6.ID, k7.ID, k8.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#Code"}}, want: []int{k7.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#c?d*"}}, want: []int{k8.ID}},
+		{in: kokizami.KizamiQuery{Tags: []string{"#c[o]de"}}, want: []int{}},
+	}
+	for i, tc := range tcs {
+		q := tc.in
+		ks, err := r.KizamiRepo.Find(&q)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(ids(ks), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func testInterval(t *testing.T, r *Repositories) {
+	k := insert(t, r, "hoge", at(9, 0), initialTime())
+
+	i2 := &kokizami.Interval{KizamiID: k.ID, StartedAt: at(10, 0), StoppedAt: initialTime()}
+	i1 := &kokizami.Interval{KizamiID: k.ID, StartedAt: at(9, 0), StoppedAt: at(9, 30)}
+	for _, i := range []*kokizami.Interval{i2, i1} {
+		err := r.KizamiRepo.InsertInterval(i)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	if i1.ID == 0 || i1.ID == i2.ID {
+		t.Fatalf("unexpected result: [got] %v, %v [want] unique IDs", i1.ID, i2.ID)
+	}
+
+	i2.StoppedAt = at(11, 0)
+	err := r.KizamiRepo.UpdateInterval(i2)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	got, err := r.KizamiRepo.FindByID(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	// intervals are sorted by started_at
+	want := []kokizami.Interval{*i1, *i2}
+	if diff := cmp.Diff(got.Intervals, want, equateTime); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ks, err := r.KizamiRepo.FindAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(ks[0].Intervals, want, equateTime); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = r.KizamiRepo.InsertInterval(&kokizami.Interval{KizamiID: k.ID + 100, StartedAt: at(9, 0)})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	err = r.KizamiRepo.DeleteInterval(i1)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got, err = r.KizamiRepo.FindByID(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(got.Intervals, []kokizami.Interval{*i2}, equateTime); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = r.KizamiRepo.DeleteInterval(i1)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func testTag(t *testing.T, r *Repositories) {
+	err := r.TagRepo.Insert([]string{"#a", "", "#b"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	// labels are unique
+	err = r.TagRepo.Insert([]string{"#b", "#c"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ts, err := r.TagRepo.FindAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(labels(ts), []string{"#a", "#b", "#c"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ts, err = r.TagRepo.FindByLabels([]string{"#c", "#a", "#d"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(labels(ts), []string{"#a", "#c"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ts, err = r.TagRepo.FindByLabels(nil)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ts), 0)
+	}
+
+	tag, err := r.TagRepo.FindByID(tagByLabel(t, r, "#b").ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if tag.Label != "#b" {
+		t.Fatalf("unexpected result: [got] %v [want] %v", tag.Label, "#b")
+	}
+
+	err = r.TagRepo.Delete(tag.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = r.TagRepo.FindByID(tag.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	err = r.TagRepo.Delete(tag.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+// tagByLabel returns a tag that has specified label
+func tagByLabel(t *testing.T, r *Repositories, label string) *kokizami.Tag {
+	t.Helper()
+	ts, err := r.TagRepo.FindByLabels([]string{label})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ts), 1)
+	}
+	return ts[0]
+}
+
+func testTagging(t *testing.T, r *Repositories) {
+	k1 := insert(t, r, "hoge #a #b", at(9, 0), at(10, 0))
+	k2 := insert(t, r, "fuga #b", at(10, 0), at(11, 0))
+	tagging(t, r, k1, "#a", "#b")
+	tagging(t, r, k2, "#b")
+	// tagging twice is no-op
+	tagging(t, r, k2, "#b")
+
+	byKizamiID := func(id int) []string {
+		t.Helper()
+		ts, err := r.TagRepo.FindByKizamiID(id)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		return labels(ts)
+	}
+
+	if diff := cmp.Diff(byKizamiID(k1.ID), []string{"#a", "#b"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+	if diff := cmp.Diff(byKizamiID(k2.ID), []string{"#b"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// kizamis without tags are not contained
+	k3 := insert(t, r, "piyo", at(11, 0), at(12, 0))
+	tss, err := r.TagRepo.FindByKizamiIDs([]int{k1.ID, k2.ID, k3.ID, k3.ID + 100})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := map[int][]string{}
+	for id, ts := range tss {
+		got[id] = labels(ts)
+	}
+	if diff := cmp.Diff(got, map[int][]string{k1.ID: {"#a", "#b"}, k2.ID: {"#b"}}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = r.KizamiRepo.Untagging(k2.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(byKizamiID(k2.ID), []string{}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// relations are deleted with tags
+	err = r.TagRepo.Delete(tagByLabel(t, r, "#a").ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(byKizamiID(k1.ID), []string{"#b"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// relations are deleted with kizamis
+	err = r.KizamiRepo.Delete(k1)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(byKizamiID(k1.ID), []string{}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = r.KizamiRepo.Tagging(k2.ID, []int{tagByLabel(t, r, "#b").ID + 100})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+// prepareSummary inserts kizamis on 2019-05-01 as follows
+//
+//	hoge #a #b 09:00-10:00
+//	fuga #b    10:00-12:00
+//	piyo       23:00-01:00 (next day)
+//	paused #a  13:00-14:30, paused 13:30-14:00
+//	running #c 20:00-
+func prepareSummary(t *testing.T, r *Repositories) {
+	k := insert(t, r, "hoge #a #b", at(9, 0), at(10, 0))
+	tagging(t, r, k, "#a", "#b")
+	k = insert(t, r, "fuga #b", at(10, 0), at(12, 0))
+	tagging(t, r, k, "#b")
+	_ = insert(t, r, "piyo", at(23, 0), at(25, 0))
+	k = insert(t, r, "paused #a", at(13, 0), at(14, 30))
+	tagging(t, r, k, "#a")
+	for _, i := range []*kokizami.Interval{
+		{KizamiID: k.ID, StartedAt: at(13, 0), StoppedAt: at(13, 30)},
+		{KizamiID: k.ID, StartedAt: at(14, 0), StoppedAt: at(14, 30)},
+	} {
+		err := r.KizamiRepo.InsertInterval(i)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	k = insert(t, r, "running #c", at(20, 0), initialTime())
+	tagging(t, r, k, "#c")
+}
+
+// ignoreDesc ignores desc of Elapsed grouped by tag since it is not specified
+var ignoreDesc = cmp.Transformer("ignoreDesc", func(e kokizami.Elapsed) kokizami.Elapsed {
+	e.Desc = ""
+	return e
+})
+
+func testSummary(t *testing.T, r *Repositories) {
+	prepareSummary(t, r)
+
+	from, to := base, base.Add(24*time.Hour)
+	now := at(22, 0)
+
+	tcs := []struct {
+		inOpts    kokizami.SummaryOptions
+		wantByTag []*kokizami.Elapsed
+		wantTotal *kokizami.Elapsed
+	}{
+		{
+			inOpts: kokizami.SummaryOptions{Now: now},
+			wantByTag: []*kokizami.Elapsed{
+				{Tag: "", Count: 1, Elapsed: time.Hour},
+				{Tag: "#a", Count: 2, Elapsed: 2 * time.Hour},
+				{Tag: "#b", Count: 2, Elapsed: 3 * time.Hour},
+			},
+			wantTotal: &kokizami.Elapsed{Count: 4, Elapsed: 5 * time.Hour},
+		},
+		{
+			inOpts: kokizami.SummaryOptions{Now: now, IncludeRunning: true},
+			wantByTag: []*kokizami.Elapsed{
+				{Tag: "", Count: 1, Elapsed: time.Hour},
+				{Tag: "#a", Count: 2, Elapsed: 2 * time.Hour},
+				{Tag: "#b", Count: 2, Elapsed: 3 * time.Hour},
+				{Tag: "#c", Count: 1, Elapsed: 2 * time.Hour, InProgress: true},
+			},
+			wantTotal: &kokizami.Elapsed{Count: 5, Elapsed: 7 * time.Hour, InProgress: true},
+		},
+		{
+			inOpts: kokizami.SummaryOptions{Now: now, Attribution: kokizami.AttributionSplit},
+			wantByTag: []*kokizami.Elapsed{
+				{Tag: "", Count: 1, Elapsed: time.Hour},
+				{Tag: "#a", Count: 2, Elapsed: 90 * time.Minute},
+				{Tag: "#b", Count: 2, Elapsed: 150 * time.Minute},
+			},
+			wantTotal: &kokizami.Elapsed{Count: 4, Elapsed: 5 * time.Hour},
+		},
+		{
+			inOpts: kokizami.SummaryOptions{Now: now, Attribution: kokizami.AttributionPrimary},
+			wantByTag: []*kokizami.Elapsed{
+				{Tag: "", Count: 1, Elapsed: time.Hour},
+				{Tag: "#a", Count: 2, Elapsed: 2 * time.Hour},
+				{Tag: "#b", Count: 1, Elapsed: 2 * time.Hour},
+			},
+			wantTotal: &kokizami.Elapsed{Count: 4, Elapsed: 5 * time.Hour},
+		},
+	}
+
+	for i, tc := range tcs {
+		opts := tc.inOpts
+		got, err := r.SummaryRepo.ElapsedByTag(from, to, &opts)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.wantByTag, ignoreDesc); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+
+		total, err := r.SummaryRepo.ElapsedTotal(from, to, &opts)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(total, tc.wantTotal); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// each desc is counted once with its primary tag regardless of attribution
+	want := []*kokizami.Elapsed{
+		{Tag: "#b", Desc: "fuga #b", Count: 1, Elapsed: 2 * time.Hour},
+		{Tag: "#a", Desc: "hoge #a #b", Count: 1, Elapsed: time.Hour},
+		{Tag: "#a", Desc: "paused #a", Count: 1, Elapsed: time.Hour},
+		{Tag: "", Desc: "piyo", Count: 1, Elapsed: time.Hour},
+	}
+	for i, a := range []kokizami.Attribution{kokizami.AttributionFull, kokizami.AttributionSplit, kokizami.AttributionPrimary} {
+		got, err := r.SummaryRepo.ElapsedByDesc(from, to, &kokizami.SummaryOptions{Now: now, Attribution: a})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func testSummaryClip(t *testing.T, r *Repositories) {
+	prepareSummary(t, r)
+
+	// paused term and terms out of range are not counted
+	from, to := at(9, 30), at(14, 15)
+
+	got, err := r.SummaryRepo.ElapsedByTag(from, to, &kokizami.SummaryOptions{Now: at(22, 0)})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := []*kokizami.Elapsed{
+		{Tag: "#a", Count: 2, Elapsed: 75 * time.Minute},
+		{Tag: "#b", Count: 2, Elapsed: 150 * time.Minute},
+	}
+	if diff := cmp.Diff(got, want, ignoreDesc); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// empty range
+	total, err := r.SummaryRepo.ElapsedTotal(at(30, 0), at(31, 0), &kokizami.SummaryOptions{Now: at(22, 0)})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(total, &kokizami.Elapsed{}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func testSummaryDepth(t *testing.T, r *Repositories) {
+	k := insert(t, r, "api #acme/backend #acme/frontend", at(9, 0), at(10, 0))
+	tagging(t, r, k, "#acme/backend", "#acme/frontend")
+	k = insert(t, r, "deploy #acme/backend/infra", at(10, 0), at(12, 0))
+	tagging(t, r, k, "#acme/backend/infra")
+	k = insert(t, r, "meeting #acme #other/x", at(13, 0), at(13, 30))
+	tagging(t, r, k, "#acme", "#other/x")
+
+	from, to := base, base.Add(24*time.Hour)
+
+	tcs := []struct {
+		inOpts kokizami.SummaryOptions
+		want   []*kokizami.Elapsed
+	}{
+		{
+			// a kizami is counted once for an ancestor
+			inOpts: kokizami.SummaryOptions{Depth: 1},
+			want: []*kokizami.Elapsed{
+				{Tag: "#acme", Count: 3, Elapsed: 210 * time.Minute},
+				{Tag: "#other", Count: 1, Elapsed: 30 * time.Minute},
+			},
+		},
+		{
+			inOpts: kokizami.SummaryOptions{Depth: 2},
+			want: []*kokizami.Elapsed{
+				{Tag: "#acme", Count: 1, Elapsed: 30 * time.Minute},
+				{Tag: "#acme/backend", Count: 2, Elapsed: 3 * time.Hour},
+				{Tag: "#acme/frontend", Count: 1, Elapsed: time.Hour},
+				{Tag: "#other/x", Count: 1, Elapsed: 30 * time.Minute},
+			},
+		},
+		{
+			// elapsed time is split among distinct ancestors
+			inOpts: kokizami.SummaryOptions{Depth: 1, Attribution: kokizami.AttributionSplit},
+			want: []*kokizami.Elapsed{
+				{Tag: "#acme", Count: 3, Elapsed: 195 * time.Minute},
+				{Tag: "#other", Count: 1, Elapsed: 15 * time.Minute},
+			},
+		},
+		{
+			inOpts: kokizami.SummaryOptions{Depth: 1, Attribution: kokizami.AttributionPrimary},
+			want: []*kokizami.Elapsed{
+				{Tag: "#acme", Count: 3, Elapsed: 210 * time.Minute},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		opts := tc.inOpts
+		got, err := r.SummaryRepo.ElapsedByTag(from, to, &opts)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want, ignoreDesc); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func testTransaction(t *testing.T, r *Repositories) {
+	if r.TxRepo == nil {
+		t.Skip("TransactionRepository is not implemented")
+	}
+
+	k := insert(t, r, "hoge", at(9, 0), at(10, 0))
+
+	for _, commit := range []bool{false, true} {
+		tx, err := r.TxRepo.Begin()
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		_, err = tx.KizamiRepo().InsertWithTime("fuga #a", at(10, 0), at(11, 0))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		// summaries in the transaction see changes in it
+		total, err := tx.SummaryRepo().ElapsedTotal(at(0, 0), at(23, 0), &kokizami.SummaryOptions{Now: at(22, 0)})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if total.Elapsed != 2*time.Hour {
+			t.Fatalf("[commit: %v] unexpected result: [got] %v [want] %v", commit, total.Elapsed, 2*time.Hour)
+		}
+		err = tx.TagRepo().Insert([]string{"#a"})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		err = tx.KizamiRepo().Delete(k)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		ks, err := r.KizamiRepo.FindAll()
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		ts, err := r.TagRepo.FindAll()
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		wantDescs, wantLabels := []string{"hoge"}, []string{}
+		if commit {
+			wantDescs, wantLabels = []string{"fuga #a"}, []string{"#a"}
+		}
+		descs := make([]string, len(ks))
+		for i := range ks {
+			descs[i] = ks[i].Desc
+		}
+		if diff := cmp.Diff(descs, wantDescs); diff != "" {
+			t.Fatalf("[commit: %v] unexpected result: (-got +want) %s", commit, diff)
+		}
+		if diff := cmp.Diff(labels(ts), wantLabels); diff != "" {
+			t.Fatalf("[commit: %v] unexpected result: (-got +want) %s", commit, diff)
+		}
+	}
+}
+
+func testEvent(t *testing.T, r *Repositories) {
+	if r.EventRepo == nil {
+		t.Skip("EventRepository is not implemented")
+	}
+
+	k := insert(t, r, "meeting", at(9, 0), at(10, 0))
+
+	for _, rid := range []string{"", "20190501T090000Z"} {
+		err := r.EventRepo.Insert("a@example.com", rid, k.ID)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	err := r.EventRepo.Insert("a@example.com", "", k.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+
+	tcs := []struct {
+		inUID          string
+		inRecurrenceID string
+		want           int
+	}{
+		{inUID: "a@example.com", inRecurrenceID: "", want: k.ID},
+		{inUID: "a@example.com", inRecurrenceID: "20190501T090000Z", want: k.ID},
+		{inUID: "a@example.com", inRecurrenceID: "20190502T090000Z", want: 0},
+		{inUID: "b@example.com", inRecurrenceID: "", want: 0},
+	}
+	for i, tc := range tcs {
+		got, err := r.EventRepo.FindKizamiID(tc.inUID, tc.inRecurrenceID)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+
+	// tracks are removed with the kizami
+	err = r.KizamiRepo.Delete(k)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got, err := r.EventRepo.FindKizamiID("a@example.com", "")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if got != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] 0", got)
+	}
+}
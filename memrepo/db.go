@@ -0,0 +1,187 @@
+// Package memrepo provides in-memory implementations of repositories of kokizami.
+// It is useful for testing and for programs that embed kokizami without sqlite.
+package memrepo
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pankona/kokizami"
+)
+
+type relation struct {
+	ID       int
+	KizamiID int
+	TagID    int
+}
+
+type importedEvent struct {
+	UID          string
+	RecurrenceID string
+	KizamiID     int
+}
+
+type data struct {
+	kizamis   []kokizami.Kizami
+	intervals []kokizami.Interval
+	tags      []kokizami.Tag
+	relations []relation
+	events    []importedEvent
+
+	lastKizamiID   int
+	lastIntervalID int
+	lastTagID      int
+	lastRelationID int
+}
+
+func (d *data) clone() data {
+	c := *d
+	c.kizamis = append([]kokizami.Kizami{}, d.kizamis...)
+	c.intervals = append([]kokizami.Interval{}, d.intervals...)
+	c.tags = append([]kokizami.Tag{}, d.tags...)
+	c.relations = append([]relation{}, d.relations...)
+	c.events = append([]importedEvent{}, d.events...)
+	return c
+}
+
+func (d *data) kizamiIndex(id int) (int, error) {
+	for i := range d.kizamis {
+		if d.kizamis[i].ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("kizami [%d] is not found", id)
+}
+
+func (d *data) tagIndex(id int) (int, error) {
+	for i := range d.tags {
+		if d.tags[i].ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("tag [%d] is not found", id)
+}
+
+// deleteRelations deletes relations that satisfy f
+func (d *data) deleteRelations(f func(r relation) bool) {
+	rs := d.relations[:0]
+	for _, r := range d.relations {
+		if !f(r) {
+			rs = append(rs, r)
+		}
+	}
+	d.relations = rs
+}
+
+// DB holds kizamis, tags, their relations and imported events in memory.
+// Repositories created with the same DB share the data.
+// DB is safe for concurrent use.
+type DB struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	data data
+}
+
+// NewDB returns an empty DB
+func NewDB() *DB {
+	return &DB{}
+}
+
+// read calls f with the data
+func (db *DB) read(f func(d *data) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return f(&db.data)
+}
+
+// write calls f with a copy of the data and stores it only if f succeeds
+// so that each operation is atomic
+func (db *DB) write(f func(d *data) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	d := db.data.clone()
+	if err := f(&d); err != nil {
+		return err
+	}
+	db.data = d
+	return nil
+}
+
+// TxRepo is an implementation of TransactionRepository in memory.
+// Transactions are serialized but not isolated from
+// operations on repositories outside of the transaction.
+type TxRepo struct {
+	db *DB
+}
+
+// NewTxRepo returns an implementation of TransactionRepository in memory
+func NewTxRepo(db *DB) *TxRepo {
+	return &TxRepo{db: db}
+}
+
+// Begin begins a transaction
+func (r *TxRepo) Begin() (kokizami.Transaction, error) {
+	r.db.txMu.Lock()
+
+	r.db.mu.Lock()
+	snapshot := r.db.data.clone()
+	r.db.mu.Unlock()
+
+	return &transaction{db: r.db, snapshot: snapshot}, nil
+}
+
+type transaction struct {
+	db       *DB
+	snapshot data
+	done     bool
+}
+
+// KizamiRepo returns KizamiRepository that operates in the transaction
+func (t *transaction) KizamiRepo() kokizami.KizamiRepository {
+	return NewKizamiRepo(t.db)
+}
+
+// TagRepo returns TagRepository that operates in the transaction
+func (t *transaction) TagRepo() kokizami.TagRepository {
+	return NewTagRepo(t.db)
+}
+
+// SummaryRepo returns SummaryRepository that operates in the transaction
+func (t *transaction) SummaryRepo() kokizami.SummaryRepository {
+	return NewSummaryRepo(t.db)
+}
+
+// SearchRepo returns nil since search is not implemented in memory
+func (t *transaction) SearchRepo() kokizami.SearchRepository {
+	return nil
+}
+
+// EventRepo returns EventRepository that operates in the transaction
+func (t *transaction) EventRepo() kokizami.EventRepository {
+	return NewEventRepo(t.db)
+}
+
+// Commit commits the transaction
+func (t *transaction) Commit() error {
+	if t.done {
+		return fmt.Errorf("transaction has already been committed or rolled back")
+	}
+	t.done = true
+	t.db.txMu.Unlock()
+	return nil
+}
+
+// Rollback restores the data as it was when the transaction began
+func (t *transaction) Rollback() error {
+	if t.done {
+		return fmt.Errorf("transaction has already been committed or rolled back")
+	}
+	t.done = true
+
+	t.db.mu.Lock()
+	t.db.data = t.snapshot
+	t.db.mu.Unlock()
+
+	t.db.txMu.Unlock()
+	return nil
+}
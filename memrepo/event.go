@@ -0,0 +1,44 @@
+package memrepo
+
+import "fmt"
+
+// EventRepo is an implementation of EventRepository in memory
+type EventRepo struct {
+	db *DB
+}
+
+// NewEventRepo returns an implementation of EventRepository in memory
+func NewEventRepo(db *DB) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+// FindKizamiID returns ID of the kizami imported from specified event, or 0 if not imported
+func (r *EventRepo) FindKizamiID(uid, recurrenceID string) (int, error) {
+	var ret int
+	err := r.db.read(func(d *data) error {
+		for _, v := range d.events {
+			if v.UID == uid && v.RecurrenceID == recurrenceID {
+				ret = v.KizamiID
+				return nil
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// Insert records that specified event is imported as the kizami
+func (r *EventRepo) Insert(uid, recurrenceID string, kizamiID int) error {
+	return r.db.write(func(d *data) error {
+		if _, err := d.kizamiIndex(kizamiID); err != nil {
+			return err
+		}
+		for _, v := range d.events {
+			if v.UID == uid && v.RecurrenceID == recurrenceID {
+				return fmt.Errorf("event [%s] [%s] has been imported already", uid, recurrenceID)
+			}
+		}
+		d.events = append(d.events, importedEvent{UID: uid, RecurrenceID: recurrenceID, KizamiID: kizamiID})
+		return nil
+	})
+}
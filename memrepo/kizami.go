@@ -0,0 +1,368 @@
+package memrepo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// KizamiRepo is an implementation of KizamiRepository in memory
+type KizamiRepo struct {
+	db  *DB
+	now func() time.Time
+}
+
+// NewKizamiRepo returns an KizamiRepo
+// as an implementation of KizamiRepository
+func NewKizamiRepo(db *DB) *KizamiRepo {
+	return &KizamiRepo{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func initialTime() time.Time {
+	return time.Unix(0, 0).UTC()
+}
+
+func isRunning(t time.Time) bool {
+	return t.Unix() == 0
+}
+
+// kizami returns a copy of specified kizami with its intervals
+func (d *data) kizami(i int) *kokizami.Kizami {
+	k := d.kizamis[i]
+	k.Intervals = nil
+	for _, v := range d.intervals {
+		if v.KizamiID == k.ID {
+			k.Intervals = append(k.Intervals, v)
+		}
+	}
+	sort.SliceStable(k.Intervals, func(i, j int) bool {
+		return k.Intervals[i].StartedAt.Before(k.Intervals[j].StartedAt)
+	})
+	return &k
+}
+
+// filter returns copies of kizamis that satisfy f
+func (d *data) filter(f func(k *kokizami.Kizami) bool) []*kokizami.Kizami {
+	ret := []*kokizami.Kizami{}
+	for i := range d.kizamis {
+		if f(&d.kizamis[i]) {
+			ret = append(ret, d.kizami(i))
+		}
+	}
+	return ret
+}
+
+// Insert inserts a kizami with specified desc
+func (r *KizamiRepo) Insert(desc string) (*kokizami.Kizami, error) {
+	return r.InsertWithTime(desc, r.now().UTC(), initialTime())
+}
+
+// InsertWithTime inserts a kizami with specified desc, started_at and stopped_at
+func (r *KizamiRepo) InsertWithTime(desc string, startedAt, stoppedAt time.Time) (*kokizami.Kizami, error) {
+	var ret *kokizami.Kizami
+	err := r.db.write(func(d *data) error {
+		d.lastKizamiID++
+		d.kizamis = append(d.kizamis, kokizami.Kizami{
+			ID:        d.lastKizamiID,
+			Desc:      desc,
+			StartedAt: startedAt.UTC(),
+			StoppedAt: stoppedAt.UTC(),
+		})
+		ret = d.kizami(len(d.kizamis) - 1)
+		return nil
+	})
+	return ret, err
+}
+
+// FindAll returns all inserted kizami
+func (r *KizamiRepo) FindAll() ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.db.read(func(d *data) error {
+		ret = d.filter(func(k *kokizami.Kizami) bool { return true })
+		return nil
+	})
+	return ret, err
+}
+
+// Update updates a kizami with specified kizami
+func (r *KizamiRepo) Update(k *kokizami.Kizami) error {
+	return r.db.write(func(d *data) error {
+		i, err := d.kizamiIndex(k.ID)
+		if err != nil {
+			return err
+		}
+		d.kizamis[i].Desc = k.Desc
+		d.kizamis[i].StartedAt = k.StartedAt.UTC()
+		d.kizamis[i].StoppedAt = k.StoppedAt.UTC()
+		return nil
+	})
+}
+
+// Delete deletes specified kizami with its intervals and relations
+func (r *KizamiRepo) Delete(k *kokizami.Kizami) error {
+	return r.db.write(func(d *data) error {
+		i, err := d.kizamiIndex(k.ID)
+		if err != nil {
+			return err
+		}
+		d.kizamis = append(d.kizamis[:i], d.kizamis[i+1:]...)
+
+		is := d.intervals[:0]
+		for _, v := range d.intervals {
+			if v.KizamiID != k.ID {
+				is = append(is, v)
+			}
+		}
+		d.intervals = is
+
+		d.deleteRelations(func(rel relation) bool { return rel.KizamiID == k.ID })
+
+		es := d.events[:0]
+		for _, v := range d.events {
+			if v.KizamiID != k.ID {
+				es = append(es, v)
+			}
+		}
+		d.events = es
+		return nil
+	})
+}
+
+// FindByID finds a kizami by specified ID
+func (r *KizamiRepo) FindByID(id int) (*kokizami.Kizami, error) {
+	var ret *kokizami.Kizami
+	err := r.db.read(func(d *data) error {
+		i, err := d.kizamiIndex(id)
+		if err != nil {
+			return err
+		}
+		ret = d.kizami(i)
+		return nil
+	})
+	return ret, err
+}
+
+// FindByStoppedAt finds kizamis that has specified stopped at
+func (r *KizamiRepo) FindByStoppedAt(t time.Time) ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.db.read(func(d *data) error {
+		ret = d.filter(func(k *kokizami.Kizami) bool { return k.StoppedAt.Equal(t) })
+		return nil
+	})
+	return ret, err
+}
+
+// FindOverlapped finds kizamis that overlap specified range.
+// The range is treated as it continues forever if stoppedAt is initial value.
+func (r *KizamiRepo) FindOverlapped(startedAt, stoppedAt time.Time) ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.db.read(func(d *data) error {
+		// compare in seconds as sqlite implementation does
+		ret = d.filter(func(k *kokizami.Kizami) bool {
+			if !isRunning(k.StoppedAt) && k.StoppedAt.Unix() <= startedAt.Unix() {
+				return false
+			}
+			return isRunning(stoppedAt) || k.StartedAt.Unix() < stoppedAt.Unix()
+		})
+		return nil
+	})
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].StartedAt.Before(ret[j].StartedAt)
+	})
+	return ret, err
+}
+
+// hasTag returns true if specified kizami has a tag of the label or its descendants
+func (d *data) hasTag(kizamiID int, label string) bool {
+	for _, rel := range d.relations {
+		if rel.KizamiID != kizamiID {
+			continue
+		}
+		if i, err := d.tagIndex(rel.TagID); err == nil && kokizami.IsSubtag(d.tags[i].Label, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// elapsedSeconds returns elapsed seconds of kizami except paused terms.
+// On-going terms are treated as stopped at now.
+func elapsedSeconds(k *kokizami.Kizami, now time.Time) int64 {
+	terms := k.Intervals
+	if len(terms) == 0 {
+		terms = []kokizami.Interval{{StartedAt: k.StartedAt, StoppedAt: k.StoppedAt}}
+	}
+	var ret int64
+	for _, t := range terms {
+		stopped := t.StoppedAt.Unix()
+		if isRunning(t.StoppedAt) {
+			stopped = now.Unix()
+		}
+		ret += stopped - t.StartedAt.Unix()
+	}
+	return ret
+}
+
+// Find finds kizamis that satisfy specified query
+func (r *KizamiRepo) Find(q *kokizami.KizamiQuery) ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.db.read(func(d *data) error {
+		ret = d.filter(func(k *kokizami.Kizami) bool {
+			// compare in seconds as sqlite implementation does
+			if !q.Since.IsZero() && !isRunning(k.StoppedAt) && k.StoppedAt.Unix() <= q.Since.Unix() {
+				return false
+			}
+			if !q.Until.IsZero() && k.StartedAt.Unix() >= q.Until.Unix() {
+				return false
+			}
+			for _, v := range q.Tags {
+				if !d.hasTag(k.ID, v) {
+					return false
+				}
+			}
+			for _, v := range q.ExcludeTags {
+				if d.hasTag(k.ID, v) {
+					return false
+				}
+			}
+			if q.Desc != "" && !strings.Contains(strings.ToLower(k.Desc), strings.ToLower(q.Desc)) {
+				return false
+			}
+			if q.Running && !isRunning(k.StoppedAt) {
+				return false
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := func(k *kokizami.Kizami) int64 {
+		switch q.OrderBy {
+		case kokizami.OrderByStartedAt:
+			return k.StartedAt.Unix()
+		case kokizami.OrderByStoppedAt:
+			if isRunning(k.StoppedAt) {
+				return q.Now.Unix()
+			}
+			return k.StoppedAt.Unix()
+		case kokizami.OrderByElapsed:
+			return elapsedSeconds(k, q.Now)
+		}
+		return 0
+	}
+	less := func(a, b *kokizami.Kizami) bool {
+		if q.OrderBy == kokizami.OrderByDesc && a.Desc != b.Desc {
+			return a.Desc < b.Desc
+		}
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka < kb
+		}
+		return a.ID < b.ID
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if q.Descending {
+			return less(ret[j], ret[i])
+		}
+		return less(ret[i], ret[j])
+	})
+
+	if q.Offset >= len(ret) {
+		return []*kokizami.Kizami{}, nil
+	}
+	ret = ret[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(ret) {
+		ret = ret[:q.Limit]
+	}
+
+	return ret, nil
+}
+
+// Tagging make relation between kizami and tags
+func (r *KizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
+	return r.db.write(func(d *data) error {
+		if _, err := d.kizamiIndex(kizamiID); err != nil {
+			return err
+		}
+
+	next:
+		for _, tagID := range tagIDs {
+			if _, err := d.tagIndex(tagID); err != nil {
+				return err
+			}
+			for _, rel := range d.relations {
+				if rel.KizamiID == kizamiID && rel.TagID == tagID {
+					continue next
+				}
+			}
+			d.lastRelationID++
+			d.relations = append(d.relations, relation{
+				ID:       d.lastRelationID,
+				KizamiID: kizamiID,
+				TagID:    tagID,
+			})
+		}
+		return nil
+	})
+}
+
+// Untagging removes all tags that are held by a kizami
+func (r *KizamiRepo) Untagging(kizamiID int) error {
+	return r.db.write(func(d *data) error {
+		d.deleteRelations(func(rel relation) bool { return rel.KizamiID == kizamiID })
+		return nil
+	})
+}
+
+// InsertInterval inserts an interval of a kizami
+func (r *KizamiRepo) InsertInterval(i *kokizami.Interval) error {
+	return r.db.write(func(d *data) error {
+		if _, err := d.kizamiIndex(i.KizamiID); err != nil {
+			return err
+		}
+		d.lastIntervalID++
+		d.intervals = append(d.intervals, kokizami.Interval{
+			ID:        d.lastIntervalID,
+			KizamiID:  i.KizamiID,
+			StartedAt: i.StartedAt.UTC(),
+			StoppedAt: i.StoppedAt.UTC(),
+		})
+		i.ID = d.lastIntervalID
+		return nil
+	})
+}
+
+// UpdateInterval updates an interval of a kizami
+func (r *KizamiRepo) UpdateInterval(i *kokizami.Interval) error {
+	return r.db.write(func(d *data) error {
+		for j := range d.intervals {
+			if d.intervals[j].ID == i.ID {
+				d.intervals[j].StartedAt = i.StartedAt.UTC()
+				d.intervals[j].StoppedAt = i.StoppedAt.UTC()
+				return nil
+			}
+		}
+		return fmt.Errorf("interval [%d] is not found", i.ID)
+	})
+}
+
+// DeleteInterval deletes an interval of a kizami
+func (r *KizamiRepo) DeleteInterval(i *kokizami.Interval) error {
+	return r.db.write(func(d *data) error {
+		for j := range d.intervals {
+			if d.intervals[j].ID == i.ID {
+				d.intervals = append(d.intervals[:j], d.intervals[j+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("interval [%d] is not found", i.ID)
+	})
+}
@@ -0,0 +1,20 @@
+package memrepo
+
+import (
+	"testing"
+
+	"github.com/pankona/kokizami/kokizamitest"
+)
+
+func TestRepositories(t *testing.T) {
+	kokizamitest.TestRepositories(t, func(t *testing.T) (*kokizamitest.Repositories, func()) {
+		db := NewDB()
+		return &kokizamitest.Repositories{
+			KizamiRepo:  NewKizamiRepo(db),
+			TagRepo:     NewTagRepo(db),
+			SummaryRepo: NewSummaryRepo(db),
+			TxRepo:      NewTxRepo(db),
+			EventRepo:   NewEventRepo(db),
+		}, func() {}
+	})
+}
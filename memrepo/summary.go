@@ -0,0 +1,221 @@
+package memrepo
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// SummaryRepo is an implementation of SummaryRepository in memory.
+// Elapsed time is calculated in seconds as sqlite implementation does.
+type SummaryRepo struct {
+	db *DB
+}
+
+// NewSummaryRepo returns a struct that implements SummaryRepository in memory
+func NewSummaryRepo(db *DB) *SummaryRepo {
+	return &SummaryRepo{db: db}
+}
+
+// span is a term that a kizami is actually on-going
+type span struct {
+	kizami  *kokizami.Kizami
+	elapsed float64
+	running bool
+}
+
+// spans returns terms of kizamis that are on-going in specified range,
+// with elapsed seconds clipped to the range
+func (d *data) spans(from, to time.Time, opts *kokizami.SummaryOptions) []span {
+	ret := []span{}
+	for i := range d.kizamis {
+		k := d.kizami(i)
+
+		terms := k.Intervals
+		if len(terms) == 0 {
+			terms = []kokizami.Interval{{StartedAt: k.StartedAt, StoppedAt: k.StoppedAt}}
+		}
+
+		for _, t := range terms {
+			started, stopped := t.StartedAt.Unix(), t.StoppedAt.Unix()
+			running := isRunning(t.StoppedAt)
+			if running {
+				if !opts.IncludeRunning {
+					continue
+				}
+				// on-going spans are treated as stopped at now
+				stopped = opts.Now.Unix()
+			}
+
+			if started >= to.Unix() || stopped <= from.Unix() {
+				continue
+			}
+
+			// spans that straddle the range boundaries are clipped to the range
+			if started < from.Unix() {
+				started = from.Unix()
+			}
+			if stopped > to.Unix() {
+				stopped = to.Unix()
+			}
+
+			ret = append(ret, span{
+				kizami:  k,
+				elapsed: float64(stopped - started),
+				running: running,
+			})
+		}
+	}
+	return ret
+}
+
+// labels returns labels of tags of specified kizami ordered by relation.
+// Primary tag is a tag that appears first in desc of the kizami.
+// Tags are rolled up to distinct ancestors at depth if depth is positive.
+func (d *data) labels(k *kokizami.Kizami, primary bool, depth int) []string {
+	ret := []string{}
+	for _, rel := range d.relations {
+		if rel.KizamiID != k.ID {
+			continue
+		}
+		i, err := d.tagIndex(rel.TagID)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, d.tags[i].Label)
+	}
+
+	if primary && len(ret) > 1 {
+		pos := func(label string) int {
+			p := strings.Index(" "+k.Desc+" ", " "+label+" ")
+			if p < 0 {
+				// tags that do not appear in desc come last
+				return len(k.Desc) + 1
+			}
+			return p
+		}
+		sort.SliceStable(ret, func(i, j int) bool {
+			return pos(ret[i]) < pos(ret[j])
+		})
+		ret = ret[:1]
+	}
+
+	if depth > 0 {
+		rolled := []string{}
+		seen := map[string]bool{}
+		for _, v := range ret {
+			v = kokizami.TagAtDepth(v, depth)
+			if !seen[v] {
+				seen[v] = true
+				rolled = append(rolled, v)
+			}
+		}
+		ret = rolled
+	}
+
+	return ret
+}
+
+func (r *SummaryRepo) elapsedBy(from, to time.Time, opts *kokizami.SummaryOptions, byDesc bool) ([]*kokizami.Elapsed, error) {
+	type group struct {
+		elapsed *kokizami.Elapsed
+		seconds float64
+		kizamis map[int]struct{}
+	}
+
+	var (
+		groups = map[[2]string]*group{}
+		keys   = [][2]string{}
+	)
+	err := r.db.read(func(d *data) error {
+		for _, s := range d.spans(from, to, opts) {
+			// each desc is counted once with its primary tag
+			primary := byDesc || opts.Attribution == kokizami.AttributionPrimary
+			labels := d.labels(s.kizami, primary, opts.Depth)
+
+			elapsed := s.elapsed
+			if !byDesc && opts.Attribution == kokizami.AttributionSplit && len(labels) > 1 {
+				elapsed /= float64(len(labels))
+			}
+			if len(labels) == 0 {
+				labels = []string{""}
+			}
+
+			for _, label := range labels {
+				key := [2]string{"", label}
+				if byDesc {
+					key[0] = s.kizami.Desc
+				}
+				g, ok := groups[key]
+				if !ok {
+					g = &group{
+						elapsed: &kokizami.Elapsed{Tag: key[1], Desc: key[0]},
+						kizamis: map[int]struct{}{},
+					}
+					groups[key] = g
+					keys = append(keys, key)
+				}
+				g.seconds += elapsed
+				g.kizamis[s.kizami.ID] = struct{}{}
+				g.elapsed.InProgress = g.elapsed.InProgress || s.running
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	ret := make([]*kokizami.Elapsed, len(keys))
+	for i, key := range keys {
+		g := groups[key]
+		g.elapsed.Count = len(g.kizamis)
+		g.elapsed.Elapsed = time.Duration(g.seconds * float64(time.Second))
+		ret[i] = g.elapsed
+	}
+
+	return ret, nil
+}
+
+// ElapsedByDesc returns an array of Elapsed time in specified range to summarize them by desc.
+// Each kizami is counted only once regardless of Attribution, and its primary tag is returned as Tag.
+func (r *SummaryRepo) ElapsedByDesc(from, to time.Time, opts *kokizami.SummaryOptions) ([]*kokizami.Elapsed, error) {
+	return r.elapsedBy(from, to, opts, true)
+}
+
+// ElapsedByTag returns an array of Elapsed time in specified range to summarize them by tag
+func (r *SummaryRepo) ElapsedByTag(from, to time.Time, opts *kokizami.SummaryOptions) ([]*kokizami.Elapsed, error) {
+	return r.elapsedBy(from, to, opts, false)
+}
+
+// ElapsedTotal returns total Elapsed time in specified range.
+// Each kizami is counted only once regardless of its tags.
+func (r *SummaryRepo) ElapsedTotal(from, to time.Time, opts *kokizami.SummaryOptions) (*kokizami.Elapsed, error) {
+	ret := &kokizami.Elapsed{}
+	err := r.db.read(func(d *data) error {
+		var seconds float64
+		kizamis := map[int]struct{}{}
+		for _, s := range d.spans(from, to, opts) {
+			seconds += s.elapsed
+			kizamis[s.kizami.ID] = struct{}{}
+			ret.InProgress = ret.InProgress || s.running
+		}
+		ret.Count = len(kizamis)
+		ret.Elapsed = time.Duration(seconds) * time.Second
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
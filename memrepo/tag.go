@@ -0,0 +1,146 @@
+package memrepo
+
+import (
+	"github.com/pankona/kokizami"
+)
+
+// TagRepo is an implementation of TagRepository in memory
+type TagRepo struct {
+	db *DB
+}
+
+// NewTagRepo returns an implementation of TagRepository in memory
+func NewTagRepo(db *DB) *TagRepo {
+	return &TagRepo{db: db}
+}
+
+// FindByID finds a tag with specified ID
+func (t *TagRepo) FindByID(id int) (*kokizami.Tag, error) {
+	var ret *kokizami.Tag
+	err := t.db.read(func(d *data) error {
+		i, err := d.tagIndex(id)
+		if err != nil {
+			return err
+		}
+		tag := d.tags[i]
+		ret = &tag
+		return nil
+	})
+	return ret, err
+}
+
+// FindAll returns all tags
+func (t *TagRepo) FindAll() ([]*kokizami.Tag, error) {
+	return t.filter(func(tag *kokizami.Tag) bool { return true })
+}
+
+// FindByKizamiID returns tags they are held by specified kizami
+func (t *TagRepo) FindByKizamiID(kizamiID int) ([]*kokizami.Tag, error) {
+	var ret []*kokizami.Tag
+	err := t.db.read(func(d *data) error {
+		ret = []*kokizami.Tag{}
+		for _, rel := range d.relations {
+			if rel.KizamiID != kizamiID {
+				continue
+			}
+			i, err := d.tagIndex(rel.TagID)
+			if err != nil {
+				continue
+			}
+			tag := d.tags[i]
+			ret = append(ret, &tag)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// FindByKizamiIDs returns tags they are held by each of specified kizamis
+func (t *TagRepo) FindByKizamiIDs(kizamiIDs []int) (map[int][]*kokizami.Tag, error) {
+	ids := make(map[int]struct{}, len(kizamiIDs))
+	for _, v := range kizamiIDs {
+		ids[v] = struct{}{}
+	}
+
+	var ret map[int][]*kokizami.Tag
+	err := t.db.read(func(d *data) error {
+		ret = map[int][]*kokizami.Tag{}
+		for _, rel := range d.relations {
+			if _, ok := ids[rel.KizamiID]; !ok {
+				continue
+			}
+			i, err := d.tagIndex(rel.TagID)
+			if err != nil {
+				continue
+			}
+			tag := d.tags[i]
+			ret[rel.KizamiID] = append(ret[rel.KizamiID], &tag)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// FindByLabels finds tags by specified labels
+func (t *TagRepo) FindByLabels(labels []string) ([]*kokizami.Tag, error) {
+	m := make(map[string]struct{}, len(labels))
+	for _, v := range labels {
+		m[v] = struct{}{}
+	}
+	return t.filter(func(tag *kokizami.Tag) bool {
+		_, ok := m[tag.Label]
+		return ok
+	})
+}
+
+func (t *TagRepo) filter(f func(tag *kokizami.Tag) bool) ([]*kokizami.Tag, error) {
+	var ret []*kokizami.Tag
+	err := t.db.read(func(d *data) error {
+		ret = []*kokizami.Tag{}
+		for i := range d.tags {
+			if f(&d.tags[i]) {
+				tag := d.tags[i]
+				ret = append(ret, &tag)
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// Insert inserts tags with specified labels.
+// Empty labels and labels that already exist are ignored.
+func (t *TagRepo) Insert(labels []string) error {
+	return t.db.write(func(d *data) error {
+	next:
+		for _, label := range labels {
+			if len(label) == 0 {
+				continue
+			}
+			for _, tag := range d.tags {
+				if tag.Label == label {
+					continue next
+				}
+			}
+			d.lastTagID++
+			d.tags = append(d.tags, kokizami.Tag{
+				ID:    d.lastTagID,
+				Label: label,
+			})
+		}
+		return nil
+	})
+}
+
+// Delete deletes a tag by specified ID with its relations
+func (t *TagRepo) Delete(id int) error {
+	return t.db.write(func(d *data) error {
+		i, err := d.tagIndex(id)
+		if err != nil {
+			return err
+		}
+		d.tags = append(d.tags[:i], d.tags[i+1:]...)
+		d.deleteRelations(func(rel relation) bool { return rel.TagID == id })
+		return nil
+	})
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Elapsed represents elapsed time, that are
+// calculated from all kizami items with specified term
+type Elapsed struct {
+	Tag        string
+	Desc       string
+	Count      int
+	Elapsed    time.Duration
+	InProgress bool
+}
+
+const (
+	// AttributionFull attributes full elapsed time of a kizami to each of its tags
+	AttributionFull = iota
+	// AttributionSplit splits elapsed time of a kizami evenly among its tags
+	AttributionSplit
+	// AttributionPrimary attributes elapsed time of a kizami only to its primary tag,
+	// that appears first in its desc
+	AttributionPrimary
+)
+
+// ElapsedQuery represents conditions to calculate Elapsed
+type ElapsedQuery struct {
+	// From and To specify half-open range [From, To) to calculate Elapsed
+	From time.Time
+	To   time.Time
+	// IncludeRunning specifies to count on-going kizamis up to Now
+	IncludeRunning bool
+	Now            time.Time
+	// Attribution specifies how to attribute elapsed time to tags
+	Attribution int
+	// Depth specifies to roll tags up to their ancestors at the depth if positive
+	Depth int
+}
+
+// spanTable is a common table expression of terms that kizamis are actually on-going.
+// Intervals are used for kizamis that have been paused,
+// otherwise started_at and stopped_at of kizamis are used.
+const spanTable = `WITH ` + spanCTE + ` `
+
+const spanCTE = `span AS (` +
+	`SELECT kizami_id, started_at, stopped_at FROM interval ` +
+	`UNION ALL ` +
+	`SELECT id, started_at, stopped_at FROM kizami WHERE id NOT IN (SELECT kizami_id FROM interval)` +
+	`)`
+
+// rollupTable is common table expressions to roll tags up to their ancestors at a depth.
+// segment splits labels of tags by '/' recursively up to the depth,
+// tagpath has the ancestor of each tag and rollup has distinct ancestors of each kizami.
+// The depth is given as an argument.
+const rollupTable = `WITH RECURSIVE ` +
+	`segment(id, rest, path, depth) AS (` +
+	`SELECT id, substr(label, 2) || '/', '#', 0 FROM tag ` +
+	`UNION ALL ` +
+	`SELECT id, substr(rest, instr(rest, '/') + 1), ` +
+	`path || (CASE WHEN depth = 0 THEN '' ELSE '/' END) || substr(rest, 1, instr(rest, '/') - 1), depth + 1 ` +
+	`FROM segment WHERE rest != '' AND depth < ?` +
+	`), ` +
+	`tagpath(id, label) AS (` +
+	`SELECT id, path FROM segment WHERE rest = '' OR depth = ?` +
+	`), ` +
+	`rollup(kizami_id, label) AS (` +
+	`SELECT DISTINCT relation.kizami_id, tagpath.label FROM relation ` +
+	`INNER JOIN tagpath ON tagpath.id = relation.tag_id` +
+	`), ` +
+	spanCTE + ` `
+
+// elapsedExpr returns SQL expressions of elapsed time clipped to the range,
+// whether the kizami is on-going and WHERE clause, with their arguments
+func elapsedExpr(eq *ElapsedQuery) (elapsed, running, where string, elapsedArgs, whereArgs []interface{}) {
+	started := `CAST(strftime('%s', span.started_at) AS INTEGER)`
+	stopped := `CAST(strftime('%s', span.stopped_at) AS INTEGER)`
+	running = `span.stopped_at LIKE '1970-%'`
+	excludeRunning := ` AND NOT ` + running
+
+	var stoppedArgs []interface{}
+	if eq.IncludeRunning {
+		// on-going spans are treated as stopped at now
+		stopped = `(CASE WHEN ` + running + ` THEN ? ELSE ` + stopped + ` END)`
+		stoppedArgs = []interface{}{eq.Now.Unix()}
+		excludeRunning = ""
+	}
+
+	// spans that straddle the range boundaries are clipped to the range
+	elapsed = `(MIN(` + stopped + `, ?) - MAX(` + started + `, ?))`
+	elapsedArgs = append(elapsedArgs, stoppedArgs...)
+	elapsedArgs = append(elapsedArgs, eq.To.Unix(), eq.From.Unix())
+
+	where = started + ` < ? AND ` + stopped + ` > ?` + excludeRunning
+	whereArgs = append(whereArgs, eq.To.Unix())
+	whereArgs = append(whereArgs, stoppedArgs...)
+	whereArgs = append(whereArgs, eq.From.Unix())
+
+	return elapsed, running, where, elapsedArgs, whereArgs
+}
+
+// primaryRelation is a sub query to select ID of relation to the primary tag of a kizami.
+// Primary tag is a tag that appears first in desc of the kizami.
+const primaryRelation = `SELECT r.id FROM relation AS r ` +
+	`INNER JOIN tag AS t ON t.id = r.tag_id ` +
+	`INNER JOIN kizami AS k ON k.id = r.kizami_id ` +
+	`WHERE r.kizami_id = kizami.id ` +
+	`ORDER BY instr(' ' || k.desc || ' ', ' ' || t.label || ' ') = 0, ` +
+	`instr(' ' || k.desc || ' ', ' ' || t.label || ' '), r.id ` +
+	`LIMIT 1`
+
+func elapsedBy(db XODB, eq *ElapsedQuery, groupBy string) ([]*Elapsed, error) {
+	elapsed, running, where, elapsedArgs, whereArgs := elapsedExpr(eq)
+
+	var (
+		with     = spanTable
+		withArgs []interface{}
+		related  = `relation`
+		join     = `LEFT JOIN relation ON kizami.id = relation.kizami_id ` +
+			`LEFT JOIN tag      ON tag.id    = relation.tag_id `
+	)
+	if eq.Depth > 0 {
+		// ancestors of tags are joined as tag instead, so that
+		// a kizami is counted once for an ancestor
+		with = rollupTable
+		withArgs = []interface{}{eq.Depth, eq.Depth}
+		related = `rollup`
+		join = `LEFT JOIN rollup AS tag ON kizami.id = tag.kizami_id `
+	}
+
+	switch eq.Attribution {
+	case AttributionSplit:
+		elapsed = elapsed + ` * 1.0 / MAX(1, (SELECT COUNT(*) FROM ` + related + ` AS r WHERE r.kizami_id = kizami.id))`
+	case AttributionPrimary:
+		join = `LEFT JOIN relation ON kizami.id = relation.kizami_id AND relation.id = (` + primaryRelation + `) ` +
+			`LEFT JOIN tag      ON tag.id    = relation.tag_id `
+		if eq.Depth > 0 {
+			join = `LEFT JOIN relation ON kizami.id = relation.kizami_id AND relation.id = (` + primaryRelation + `) ` +
+				`LEFT JOIN tagpath AS tag ON tag.id = relation.tag_id `
+		}
+	}
+
+	sqlstr := with +
+		`SELECT ` +
+		`tag.label AS tag, desc, count(DISTINCT kizami.id), ` +
+		`SUM(` + elapsed + `) AS elapsed, ` +
+		`MAX(` + running + `) AS in_progress ` +
+		`FROM span ` +
+		`INNER JOIN kizami ON kizami.id = span.kizami_id ` +
+		join +
+		`WHERE ` + where + ` ` +
+		`GROUP BY ` + groupBy // #nosec
+
+	args := append(withArgs, elapsedArgs...)
+	args = append(args, whereArgs...)
+
+	XOLog(sqlstr, args...)
+	q, err := db.Query(sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	res := []*Elapsed{}
+	var (
+		sec float64
+		tag sql.NullString
+	)
+	for q.Next() {
+		e := Elapsed{}
+
+		err = q.Scan(&tag, &e.Desc, &e.Count, &sec, &e.InProgress)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Tag = ""
+		// #nosec
+		if v, _ := tag.Value(); v != nil {
+			e.Tag = v.(string)
+		}
+		e.Elapsed = time.Duration(sec * float64(time.Second))
+
+		res = append(res, &e)
+	}
+
+	return res, nil
+}
+
+// ElapsedByDesc returns each all kizami's total elapsed time
+// elapsed in specified range group by desc. Each kizami is counted only once
+// regardless of Attribution, and its primary tag is returned as Tag.
+func ElapsedByDesc(db XODB, eq *ElapsedQuery) ([]*Elapsed, error) {
+	byDesc := *eq
+	byDesc.Attribution = AttributionPrimary
+	return elapsedBy(db, &byDesc, "desc")
+}
+
+// ElapsedByTag returns each all kizami's total
+// elapsed time elapsed in specified range group by tag
+func ElapsedByTag(db XODB, eq *ElapsedQuery) ([]*Elapsed, error) {
+	return elapsedBy(db, eq, "tag")
+}
+
+// ElapsedTotal returns total elapsed time of all kizamis
+// elapsed in specified range. Each kizami is counted only once
+// regardless of its tags.
+func ElapsedTotal(db XODB, eq *ElapsedQuery) (*Elapsed, error) {
+	elapsed, running, where, elapsedArgs, whereArgs := elapsedExpr(eq)
+
+	sqlstr := spanTable +
+		`SELECT ` +
+		`count(DISTINCT span.kizami_id), IFNULL(SUM(` + elapsed + `), 0), IFNULL(MAX(` + running + `), 0) ` +
+		`FROM span ` +
+		`WHERE ` + where // #nosec
+
+	args := append(elapsedArgs, whereArgs...)
+
+	XOLog(sqlstr, args...)
+	var (
+		e   Elapsed
+		sec int64
+	)
+	err := db.QueryRow(sqlstr, args...).Scan(&e.Count, &sec, &e.InProgress)
+	if err != nil {
+		return nil, err
+	}
+	e.Elapsed = time.Duration(sec) * time.Second
+
+	return &e, nil
+}
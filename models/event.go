@@ -0,0 +1,47 @@
+package models
+
+import "database/sql"
+
+// CreateImportedEventTable creates table that tracks calendar events imported as kizamis.
+// An event is identified by its UID and recurrence ID, that is empty for non-recurring events.
+// Rows are deleted with kizamis so that deleted ones can be imported again.
+func CreateImportedEventTable(db XODB) error {
+	// sql query
+	const sqlstr = "CREATE TABLE IF NOT EXISTS imported_event (" +
+		" uid TEXT NOT NULL" +
+		", recurrence_id TEXT NOT NULL DEFAULT ''" +
+		", kizami_id INTEGER NOT NULL REFERENCES kizami(id) ON DELETE CASCADE" +
+		", imported_at TIMESTAMP DEFAULT (DATETIME('now'))" +
+		", PRIMARY KEY(uid, recurrence_id)" +
+		")"
+	XOLog(sqlstr)
+	_, err := db.Exec(sqlstr)
+	return err
+}
+
+// ImportedEventKizamiID returns ID of the kizami imported from specified event.
+// 0 is returned if the event has not been imported.
+func ImportedEventKizamiID(db XODB, uid, recurrenceID string) (int, error) {
+	// sql query
+	const sqlstr = `SELECT kizami_id FROM imported_event WHERE uid = ? AND recurrence_id = ?`
+
+	// run query
+	XOLog(sqlstr, uid, recurrenceID)
+	var id int
+	err := db.QueryRow(sqlstr, uid, recurrenceID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// InsertImportedEvent records that specified event is imported as the kizami
+func InsertImportedEvent(db XODB, uid, recurrenceID string, kizamiID int) error {
+	// sql query
+	const sqlstr = `INSERT INTO imported_event (uid, recurrence_id, kizami_id) VALUES (?, ?, ?)`
+
+	// run query
+	XOLog(sqlstr, uid, recurrenceID, kizamiID)
+	_, err := db.Exec(sqlstr, uid, recurrenceID, kizamiID)
+	return err
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateIntervalTable creates table and index for Interval model
+func CreateIntervalTable(db XODB) error {
+	// sql query
+	sqlstr := "CREATE TABLE IF NOT EXISTS interval (" +
+		" id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL" +
+		", kizami_id INTEGER NOT NULL" +
+		", started_at TIMESTAMP DEFAULT (DATETIME('now'))" +
+		", stopped_at TIMESTAMP DEFAULT (DATETIME('1970-01-01'))" +
+		")"
+	XOLog(sqlstr)
+	_, err := db.Exec(sqlstr)
+	if err != nil {
+		return err
+	}
+
+	sqlstr = "CREATE INDEX IF NOT EXISTS index_interval_kizami_id ON interval(kizami_id)"
+	_, err = db.Exec(sqlstr)
+	return err
+}
+
+// AllIntervals returns all Interval from interval table
+func AllIntervals(db XODB) ([]*Interval, error) {
+	// sql query
+	const sqlstr = `SELECT ` +
+		`id, kizami_id, started_at, stopped_at ` +
+		`FROM interval ` +
+		`ORDER BY started_at`
+
+	return queryIntervals(db, sqlstr)
+}
+
+// IntervalsByKizamiIDs returns intervals of specified kizamis at once
+func IntervalsByKizamiIDs(db XODB, kizamiIDs []int) ([]*Interval, error) {
+	res := []*Interval{}
+	err := inChunks(kizamiIDs, func(ids []interface{}) error {
+		sqlstr := `SELECT ` +
+			`id, kizami_id, started_at, stopped_at ` +
+			`FROM interval ` +
+			`WHERE kizami_id IN (` + placeholders(len(ids)) + `) ` +
+			`ORDER BY started_at`
+
+		is, err := queryIntervals(db, sqlstr, ids...)
+		if err != nil {
+			return err
+		}
+		res = append(res, is...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func queryIntervals(db XODB, sqlstr string, args ...interface{}) ([]*Interval, error) {
+	// run query
+	XOLog(sqlstr, args...)
+	q, err := db.Query(sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	// load results
+	res := []*Interval{}
+	for q.Next() {
+		i := Interval{
+			_exists: true,
+		}
+
+		// scan
+		err = q.Scan(&i.ID, &i.KizamiID, &i.StartedAt, &i.StoppedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &i)
+	}
+
+	return res, nil
+}
+
+// DeleteIntervalsByKizamiID removes all intervals of specified kizami
+func DeleteIntervalsByKizamiID(db XODB, kizamiID int) error {
+	const sqlstr = `DELETE FROM` +
+		` interval` +
+		` WHERE kizami_id = ?`
+	XOLog(sqlstr, kizamiID)
+	_, err := db.Exec(sqlstr, kizamiID)
+	return err
+}
+
+// maxInArgs limits the number of arguments of an IN clause
+// not to exceed the limit of host parameters of sqlite3
+const maxInArgs = 500
+
+// placeholders returns n placeholders separated by comma
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// inChunks calls fn with IDs divided into chunks of maxInArgs as arguments
+func inChunks(ids []int, fn func(args []interface{}) error) error {
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxInArgs {
+			n = maxInArgs
+		}
+		args := make([]interface{}, n)
+		for i := range args {
+			args[i] = ids[i]
+		}
+		if err := fn(args); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
@@ -1,8 +1,8 @@
 package models
 
 import (
-	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -65,25 +65,10 @@ func AllKizami(db XODB) ([]*Kizami, error) {
 	return res, nil
 }
 
-// Elapsed represents elapsed time, that are
-// calculated from all kizami items with specified term
-type Elapsed struct {
-	Tag     string
-	Desc    string
-	Count   int
-	Elapsed time.Duration
-}
-
-func elapsedOfMonthBy(db XODB, yyyymm string, groupBy string) ([]*Elapsed, error) {
-	sqlstr := fmt.Sprintf(`SELECT `+
-		`tag, desc, count(desc), SUM(strftime('%%s', kizami.stopped_at) - strftime('%%s', kizami.started_at)) AS elapsed `+
-		`FROM kizami `+
-		`LEFT JOIN relation ON kizami.id = relation.kizami_id `+
-		`LEFT JOIN tag      ON tag.id    = relation.tag_id `+
-		`WHERE started_at LIKE '%s-%%' AND stopped_at NOT LIKE '1970-%%' `+
-		`GROUP BY %s`, yyyymm, groupBy) // #nosec
-	XOLog(sqlstr)
-	q, err := db.Query(sqlstr)
+func queryKizamis(db XODB, sqlstr string, args ...interface{}) ([]*Kizami, error) {
+	// run query
+	XOLog(sqlstr, args...)
+	q, err := db.Query(sqlstr, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -94,40 +79,188 @@ func elapsedOfMonthBy(db XODB, yyyymm string, groupBy string) ([]*Elapsed, error
 		}
 	}()
 
-	res := []*Elapsed{}
-	var (
-		sec int64
-		tag sql.NullString
-	)
+	// load results
+	res := []*Kizami{}
 	for q.Next() {
-		e := Elapsed{}
+		k := Kizami{
+			_exists: true,
+		}
 
-		err = q.Scan(&tag, &e.Desc, &e.Count, &sec)
+		// scan
+		err = q.Scan(&k.ID, &k.Desc, &k.StartedAt, &k.StoppedAt)
 		if err != nil {
 			return nil, err
 		}
 
-		e.Tag = ""
-		// #nosec
-		if v, _ := tag.Value(); v != nil {
-			e.Tag = v.(string)
-		}
-		e.Elapsed = time.Duration(sec) * time.Second
-
-		res = append(res, &e)
+		res = append(res, &k)
 	}
 
 	return res, nil
 }
 
-// ElapsedOfMonthByDesc returns each all kizami's total elapsed time
-// elapsed in specified month group by desc and tag
-func ElapsedOfMonthByDesc(db XODB, yyyymm string) ([]*Elapsed, error) {
-	return elapsedOfMonthBy(db, yyyymm, "desc, tag")
+// KizamisOverlapped returns kizamis that overlap specified range [startedAt, stoppedAt).
+// On-going kizamis are treated as they continue forever.
+// The range is treated as it continues forever if openEnded is true.
+func KizamisOverlapped(db XODB, startedAt, stoppedAt time.Time, openEnded bool) ([]*Kizami, error) {
+	sqlstr := `SELECT ` +
+		`id, desc, started_at, stopped_at ` +
+		`FROM kizami ` +
+		`WHERE (stopped_at LIKE '1970-%' OR CAST(strftime('%s', stopped_at) AS INTEGER) > ?)`
+	args := []interface{}{startedAt.Unix()}
+
+	if !openEnded {
+		sqlstr += ` AND CAST(strftime('%s', started_at) AS INTEGER) < ?`
+		args = append(args, stoppedAt.Unix())
+	}
+	sqlstr += ` ORDER BY started_at`
+
+	return queryKizamis(db, sqlstr, args...)
+}
+
+const (
+	// OrderByID orders kizamis by ID
+	OrderByID = iota
+	// OrderByStartedAt orders kizamis by started_at
+	OrderByStartedAt
+	// OrderByStoppedAt orders kizamis by stopped_at. on-going kizamis come last
+	OrderByStoppedAt
+	// OrderByElapsed orders kizamis by elapsed time except paused terms
+	OrderByElapsed
+	// OrderByDesc orders kizamis by desc
+	OrderByDesc
+)
+
+// KizamiQuery represents conditions to find kizamis
+type KizamiQuery struct {
+	// Since and Until specify half-open range [Since, Until) that kizamis overlap.
+	// Zero value means unbounded.
+	Since time.Time
+	Until time.Time
+	// Tags are labels of tags that kizamis must have all of.
+	// A tag matches its descendants, e.g. #a matches #a/b.
+	Tags []string
+	// ExcludeTags are labels of tags that kizamis must not have any of,
+	// including their descendants
+	ExcludeTags []string
+	// Desc is a substring that desc of kizamis must contain, case-insensitively
+	Desc string
+	// Running specifies to find only on-going kizamis
+	Running bool
+	// Now is used as stopped_at of on-going kizamis to order them
+	Now time.Time
+	// OrderBy is one of OrderByXXX. Ties are ordered by ID.
+	OrderBy    int
+	Descending bool
+	// Limit is the maximum number of kizamis. 0 means no limit.
+	Limit  int
+	Offset int
+}
+
+// escapeLike escapes wildcards of LIKE operator with '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// escapeGlob escapes wildcards of GLOB operator by enclosing them in brackets
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`[`, `[[]`, `*`, `[*]`, `?`, `[?]`).Replace(s)
 }
 
-// ElapsedOfMonthByTag returns each all kizami's total
-// elapsed time elapsed in specified month group by tag
-func ElapsedOfMonthByTag(db XODB, yyyymm string) ([]*Elapsed, error) {
-	return elapsedOfMonthBy(db, yyyymm, "tag")
+// KizamisByQuery returns kizamis that satisfy specified query
+func KizamisByQuery(db XODB, kq *KizamiQuery) ([]*Kizami, error) {
+	const (
+		started = `CAST(strftime('%s', kizami.started_at) AS INTEGER)`
+		stopped = `CAST(strftime('%s', kizami.stopped_at) AS INTEGER)`
+		running = `kizami.stopped_at LIKE '1970-%'`
+		// relatedTo is a sub query to select kizamis that have a tag of labels
+		relatedTo = `SELECT 1 FROM relation ` +
+			`INNER JOIN tag ON tag.id = relation.tag_id ` +
+			`WHERE relation.kizami_id = kizami.id AND `
+		// subtree matches a tag and its descendants.
+		// GLOB is used since it is case-sensitive as = is, unlike LIKE.
+		subtree = `(tag.label = ? OR tag.label GLOB ?)`
+	)
+
+	var (
+		where []string
+		args  []interface{}
+	)
+
+	if !kq.Since.IsZero() {
+		where = append(where, `(`+running+` OR `+stopped+` > ?)`)
+		args = append(args, kq.Since.Unix())
+	}
+	if !kq.Until.IsZero() {
+		where = append(where, started+` < ?`)
+		args = append(args, kq.Until.Unix())
+	}
+	for _, v := range kq.Tags {
+		where = append(where, `EXISTS (`+relatedTo+subtree+`)`)
+		args = append(args, v, escapeGlob(v)+"/*")
+	}
+	if len(kq.ExcludeTags) > 0 {
+		where = append(where, `NOT EXISTS (`+relatedTo+`(`+subtree+strings.Repeat(` OR `+subtree, len(kq.ExcludeTags)-1)+`))`)
+		for _, v := range kq.ExcludeTags {
+			args = append(args, v, escapeGlob(v)+"/*")
+		}
+	}
+	if kq.Desc != "" {
+		where = append(where, `kizami.desc LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(kq.Desc)+"%")
+	}
+	if kq.Running {
+		where = append(where, running)
+	}
+
+	var (
+		orderBy   string
+		orderArgs []interface{}
+	)
+	switch kq.OrderBy {
+	case OrderByStartedAt:
+		orderBy = started
+	case OrderByStoppedAt:
+		orderBy = `(CASE WHEN ` + running + ` THEN ? ELSE ` + stopped + ` END)`
+		orderArgs = append(orderArgs, kq.Now.Unix())
+	case OrderByElapsed:
+		orderBy = `(SELECT SUM((CASE WHEN span.stopped_at LIKE '1970-%' THEN ? ` +
+			`ELSE CAST(strftime('%s', span.stopped_at) AS INTEGER) END) - ` +
+			`CAST(strftime('%s', span.started_at) AS INTEGER)) ` +
+			`FROM span WHERE span.kizami_id = kizami.id)`
+		orderArgs = append(orderArgs, kq.Now.Unix())
+	case OrderByDesc:
+		orderBy = `kizami.desc`
+	}
+
+	direction := ` ASC`
+	if kq.Descending {
+		direction = ` DESC`
+	}
+
+	sqlstr := `SELECT ` +
+		`id, desc, started_at, stopped_at ` +
+		`FROM kizami`
+	if kq.OrderBy == OrderByElapsed {
+		sqlstr = spanTable + sqlstr
+	}
+	if len(where) > 0 {
+		sqlstr += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	sqlstr += ` ORDER BY `
+	if orderBy != "" {
+		sqlstr += orderBy + direction + `, `
+		args = append(args, orderArgs...)
+	}
+	sqlstr += `kizami.id` + direction
+
+	if kq.Limit > 0 || kq.Offset > 0 {
+		limit := kq.Limit
+		if limit == 0 {
+			limit = -1
+		}
+		sqlstr += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, kq.Offset)
+	}
+
+	return queryKizamis(db, sqlstr, args...)
 }
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/xo/xoutil"
+)
+
+// Migration represents a versioned change of schema
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(db XODB) error
+}
+
+// Migrations is a list of migrations ordered by version.
+// Append a new migration to change schema. Never modify applied migrations.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create kizami, tag and relation tables",
+		Up: func(db XODB) error {
+			// tables may exist already on databases created before migration is introduced
+			if err := CreateKizamiTable(db); err != nil {
+				return err
+			}
+			if err := CreateTagTable(db); err != nil {
+				return err
+			}
+			return CreateRelationTable(db)
+		},
+	},
+	{
+		Version:     2,
+		Description: "create interval table",
+		Up:          CreateIntervalTable,
+	},
+	{
+		Version:     3,
+		Description: "add foreign keys to relation and interval tables",
+		Up:          addForeignKeys,
+	},
+	{
+		Version:     4,
+		Description: "create imported_event table",
+		Up:          CreateImportedEventTable,
+	},
+}
+
+// addForeignKeys rebuilds relation and interval tables with foreign keys
+// since sqlite can not add constraints to an existing table.
+// Orphans are removed before copying rows to satisfy the constraints.
+func addForeignKeys(db XODB) error {
+	if _, err := DeleteOrphans(db); err != nil {
+		return err
+	}
+
+	sqlstrs := []string{
+		"CREATE TABLE relation_new (" +
+			" id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL" +
+			", kizami_id INTEGER NOT NULL REFERENCES kizami(id) ON DELETE CASCADE" +
+			", tag_id INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE" +
+			", UNIQUE(kizami_id, tag_id) ON CONFLICT IGNORE" +
+			")",
+		"INSERT INTO relation_new (id, kizami_id, tag_id)" +
+			" SELECT id, kizami_id, tag_id FROM relation",
+		"DROP TABLE relation",
+		"ALTER TABLE relation_new RENAME TO relation",
+		"CREATE INDEX IF NOT EXISTS index_relation_tag_id ON relation(tag_id)",
+
+		"CREATE TABLE interval_new (" +
+			" id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL" +
+			", kizami_id INTEGER NOT NULL REFERENCES kizami(id) ON DELETE CASCADE" +
+			", started_at TIMESTAMP DEFAULT (DATETIME('now'))" +
+			", stopped_at TIMESTAMP DEFAULT (DATETIME('1970-01-01'))" +
+			")",
+		"INSERT INTO interval_new (id, kizami_id, started_at, stopped_at)" +
+			" SELECT id, kizami_id, started_at, stopped_at FROM interval",
+		"DROP TABLE interval",
+		"ALTER TABLE interval_new RENAME TO interval",
+		"CREATE INDEX IF NOT EXISTS index_interval_kizami_id ON interval(kizami_id)",
+	}
+	for _, sqlstr := range sqlstrs {
+		XOLog(sqlstr)
+		if _, err := db.Exec(sqlstr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus represents whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// CreateSchemaVersionTable creates table to record applied migrations
+func CreateSchemaVersionTable(db XODB) error {
+	// sql query
+	const sqlstr = "CREATE TABLE IF NOT EXISTS schema_version (" +
+		" version INTEGER PRIMARY KEY NOT NULL" +
+		", description VARCHAR(255) NOT NULL" +
+		", applied_at TIMESTAMP NOT NULL" +
+		")"
+	XOLog(sqlstr)
+	_, err := db.Exec(sqlstr)
+	return err
+}
+
+// appliedVersions returns applied versions and their applied time
+func appliedVersions(db XODB) (map[int]time.Time, error) {
+	const sqlstr = `SELECT version, applied_at FROM schema_version`
+	XOLog(sqlstr)
+	q, err := db.Query(sqlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	res := map[int]time.Time{}
+	for q.Next() {
+		var (
+			version   int
+			appliedAt xoutil.SqTime
+		)
+		err = q.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		res[version] = appliedAt.Time
+	}
+
+	return res, nil
+}
+
+// MigrationStatuses returns status of all migrations ordered by version
+func MigrationStatuses(db XODB) ([]*MigrationStatus, error) {
+	if err := CreateSchemaVersionTable(db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*MigrationStatus, len(Migrations))
+	for i, m := range Migrations {
+		t, ok := applied[m.Version]
+		res[i] = &MigrationStatus{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: t,
+		}
+	}
+
+	return res, nil
+}
+
+// Migrate applies migrations that have not been applied yet in order of version.
+// Each migration is applied in its own transaction.
+// Applied migrations are returned.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	ss, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []Migration{}
+	for _, s := range ss {
+		if s.Applied {
+			continue
+		}
+		if err := applyMigration(db, s.Migration); err != nil {
+			return res, fmt.Errorf("failed to apply migration %d (%s): %v", s.Version, s.Description, err)
+		}
+		res = append(res, s.Migration)
+	}
+
+	return res, nil
+}
+
+func applyMigration(db *sql.DB, m Migration) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				XOLog(fmt.Sprintf("failed to rollback: %v", e))
+			}
+		}
+	}()
+
+	err = m.Up(tx)
+	if err != nil {
+		return err
+	}
+
+	const sqlstr = `INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`
+	appliedAt := SqTime(time.Now())
+	XOLog(sqlstr, m.Version, m.Description, appliedAt)
+	_, err = tx.Exec(sqlstr, m.Version, m.Description, appliedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
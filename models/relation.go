@@ -56,6 +56,55 @@ func TagsByKizamiID(db XODB, kizamiID int) ([]*Tag, error) {
 	return res, nil
 }
 
+// TagsByKizamiIDs returns tags related to each of specified kizamis at once
+func TagsByKizamiIDs(db XODB, kizamiIDs []int) (map[int][]*Tag, error) {
+	res := map[int][]*Tag{}
+	err := inChunks(kizamiIDs, func(ids []interface{}) error {
+		sqlstr := `SELECT relation.kizami_id, tag.id, tag.label` +
+			` FROM relation` +
+			` INNER JOIN tag` +
+			` ON relation.tag_id = tag.id` +
+			` WHERE kizami_id IN (` + placeholders(len(ids)) + `)` +
+			` ORDER BY relation.id`
+
+		// run query
+		XOLog(sqlstr, ids...)
+		q, err := db.Query(sqlstr, ids...)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := q.Close()
+			if e != nil {
+				XOLog(fmt.Sprintf("failed close query: %v", e))
+			}
+		}()
+
+		// load results
+		for q.Next() {
+			var kizamiID int
+			t := Tag{
+				_exists: true,
+			}
+
+			// scan
+			err = q.Scan(&kizamiID, &t.ID, &t.Label)
+			if err != nil {
+				return err
+			}
+
+			res[kizamiID] = append(res[kizamiID], &t)
+		}
+
+		return q.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // DeleteRelationsByKizamiID removes all tags from specified kizami
 func DeleteRelationsByKizamiID(db XODB, kizamiID int) error {
 	const sqlstr = `DELETE FROM` +
@@ -65,3 +114,43 @@ func DeleteRelationsByKizamiID(db XODB, kizamiID int) error {
 	_, err := db.Exec(sqlstr, kizamiID)
 	return err
 }
+
+// Orphans represents the number of rows removed by DeleteOrphans
+type Orphans struct {
+	Relations int64
+	Intervals int64
+}
+
+// DeleteOrphans removes relations and intervals
+// that refer to a kizami or a tag which no longer exists
+func DeleteOrphans(db XODB) (*Orphans, error) {
+	const relationstr = `DELETE FROM relation` +
+		` WHERE kizami_id NOT IN (SELECT id FROM kizami)` +
+		` OR tag_id NOT IN (SELECT id FROM tag)`
+	XOLog(relationstr)
+	res, err := db.Exec(relationstr)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	const intervalstr = `DELETE FROM interval` +
+		` WHERE kizami_id NOT IN (SELECT id FROM kizami)`
+	XOLog(intervalstr)
+	res, err = db.Exec(intervalstr)
+	if err != nil {
+		return nil, err
+	}
+	intervals, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Orphans{
+		Relations: relations,
+		Intervals: intervals,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// searchTriggers keep kizami_fts in sync with kizami table
+var searchTriggers = []string{
+	"CREATE TRIGGER IF NOT EXISTS kizami_fts_ai AFTER INSERT ON kizami BEGIN" +
+		" INSERT INTO kizami_fts (rowid, desc) VALUES (new.id, new.desc);" +
+		" END",
+	"CREATE TRIGGER IF NOT EXISTS kizami_fts_ad AFTER DELETE ON kizami BEGIN" +
+		" INSERT INTO kizami_fts (kizami_fts, rowid, desc) VALUES ('delete', old.id, old.desc);" +
+		" END",
+	"CREATE TRIGGER IF NOT EXISTS kizami_fts_au AFTER UPDATE OF desc ON kizami BEGIN" +
+		" INSERT INTO kizami_fts (kizami_fts, rowid, desc) VALUES ('delete', old.id, old.desc);" +
+		" INSERT INTO kizami_fts (rowid, desc) VALUES (new.id, new.desc);" +
+		" END",
+}
+
+// EnsureSearchIndex creates full-text search index of desc of kizamis
+// and triggers to maintain it if they do not exist.
+// The index is rebuilt if triggers are missing since it may be stale.
+// false is returned if sqlite3 is built without FTS5. Triggers are dropped
+// in that case so that kizami table can be modified without FTS5.
+// This is not a migration since availability of FTS5 depends on build.
+func EnsureSearchIndex(db XODB) (bool, error) {
+	// sql query
+	sqlstr := `SELECT sqlite_compileoption_used('ENABLE_FTS5')`
+	XOLog(sqlstr)
+	var fts5 bool
+	err := db.QueryRow(sqlstr).Scan(&fts5)
+	if err != nil {
+		return false, err
+	}
+	if !fts5 {
+		for _, name := range []string{"kizami_fts_ai", "kizami_fts_ad", "kizami_fts_au"} {
+			sqlstr = "DROP TRIGGER IF EXISTS " + name
+			XOLog(sqlstr)
+			if _, err := db.Exec(sqlstr); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	sqlstr = "CREATE VIRTUAL TABLE IF NOT EXISTS kizami_fts USING fts5 (" +
+		" desc, content='kizami', content_rowid='id'" +
+		")"
+	XOLog(sqlstr)
+	_, err = db.Exec(sqlstr)
+	if err != nil {
+		return false, err
+	}
+
+	sqlstr = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'kizami_fts_%'`
+	XOLog(sqlstr)
+	var n int
+	err = db.QueryRow(sqlstr).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	if n == len(searchTriggers) {
+		return true, nil
+	}
+
+	for _, sqlstr := range searchTriggers {
+		XOLog(sqlstr)
+		if _, err := db.Exec(sqlstr); err != nil {
+			return false, err
+		}
+	}
+
+	sqlstr = `INSERT INTO kizami_fts (kizami_fts) VALUES ('rebuild')`
+	XOLog(sqlstr)
+	_, err = db.Exec(sqlstr)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SearchHit represents a kizami matched by full-text search
+type SearchHit struct {
+	Kizami  *Kizami
+	Snippet string
+	Rank    float64
+}
+
+// SnippetOpen and SnippetClose enclose matched terms in snippets
+const (
+	SnippetOpen  = "["
+	SnippetClose = "]"
+)
+
+// quoteTags quotes terms start with '#' in FTS5 query,
+// since '#' is not allowed in barewords of FTS5 query syntax.
+// Quoted terms are tokenized as same as desc, so "#foo" matches with "foo".
+func quoteTags(query string) string {
+	var b strings.Builder
+	quoted := false
+	rs := []rune(query)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if r == '"' {
+			quoted = !quoted
+		}
+		if quoted || r != '#' || (i > 0 && !strings.ContainsRune(" \t\n()", rs[i-1])) {
+			b.WriteRune(r)
+			continue
+		}
+
+		j := i
+		for j < len(rs) && !strings.ContainsRune(" \t\n()\"*", rs[j]) {
+			j++
+		}
+		b.WriteString(`"` + string(rs[i:j]) + `"`)
+		i = j - 1
+	}
+	return b.String()
+}
+
+// KizamisBySearch returns kizamis whose desc matches specified FTS5 query
+// ordered by relevance. Newer kizamis come first on a tie.
+// All matched kizamis are returned if limit is 0.
+func KizamisBySearch(db XODB, query string, limit int) ([]*SearchHit, error) {
+	if limit == 0 {
+		limit = -1
+	}
+
+	// sql query
+	const sqlstr = `SELECT ` +
+		`kizami.id, kizami.desc, kizami.started_at, kizami.stopped_at` +
+		`, snippet(kizami_fts, 0, '` + SnippetOpen + `', '` + SnippetClose + `', '...', 16)` +
+		`, bm25(kizami_fts) ` +
+		`FROM kizami_fts ` +
+		`INNER JOIN kizami ON kizami.id = kizami_fts.rowid ` +
+		`WHERE kizami_fts MATCH ? ` +
+		`ORDER BY bm25(kizami_fts), kizami.id DESC ` +
+		`LIMIT ?`
+
+	// run query
+	query = quoteTags(query)
+	XOLog(sqlstr, query, limit)
+	q, err := db.Query(sqlstr, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	// load results
+	res := []*SearchHit{}
+	for q.Next() {
+		h := SearchHit{
+			Kizami: &Kizami{
+				_exists: true,
+			},
+		}
+
+		// scan
+		err = q.Scan(&h.Kizami.ID, &h.Kizami.Desc, &h.Kizami.StartedAt, &h.Kizami.StoppedAt, &h.Snippet, &h.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &h)
+	}
+
+	return res, q.Err()
+}
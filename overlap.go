@@ -0,0 +1,183 @@
+package kokizami
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OverlapPolicy represents how to treat a kizami that overlaps others
+type OverlapPolicy int
+
+const (
+	// OverlapWarn allows overlaps and reports them to OnOverlap.
+	// On-going kizamis are not reported when another on-going kizami starts after them,
+	// since running multiple kizamis together is the usual way to track parallel tasks.
+	OverlapWarn OverlapPolicy = iota
+	// OverlapReject rejects a kizami that overlaps others
+	OverlapReject
+	// OverlapTrim trims other kizamis so that they don't overlap the kizami.
+	// Kizamis that started before the kizami are stopped when the kizami starts,
+	// and kizamis that started during the kizami are started when the kizami stops.
+	// Kizamis that can not be trimmed, e.g. entirely covered by the kizami, are rejected.
+	OverlapTrim
+)
+
+// OverlapError is returned when a kizami overlaps others
+type OverlapError struct {
+	Kizami     *Kizami
+	Overlapped []*Kizami
+}
+
+func (e *OverlapError) Error() string {
+	ids := make([]string, len(e.Overlapped))
+	for i := range e.Overlapped {
+		ids[i] = strconv.Itoa(e.Overlapped[i].ID)
+	}
+	return fmt.Sprintf("kizami overlaps other kizamis [%s]", strings.Join(ids, ", "))
+}
+
+// Conflict represents a pair of kizamis that overlap each other.
+// B is nil if A's stopped_at is before its started_at.
+type Conflict struct {
+	A *Kizami
+	B *Kizami
+}
+
+// isRunning returns true if stopped_at of specified kizami is not set yet
+func isRunning(k *Kizami) bool {
+	return k.StoppedAt.Unix() == 0
+}
+
+// overlaps returns true if a and b overlap.
+// On-going kizamis are treated as they continue forever.
+func overlaps(a, b *Kizami) bool {
+	return (isRunning(b) || a.StartedAt.Before(b.StoppedAt)) &&
+		(isRunning(a) || b.StartedAt.Before(a.StoppedAt))
+}
+
+// runningTogether returns true if a is going to run together with on-going b
+func runningTogether(a, b *Kizami) bool {
+	return isRunning(a) && isRunning(b) && !a.StartedAt.Before(b.StartedAt)
+}
+
+func validateKizami(ki *Kizami) error {
+	if !isRunning(ki) && ki.StoppedAt.Before(ki.StartedAt) {
+		return fmt.Errorf("stopped_at (%v) must not be before started_at (%v)", ki.StoppedAt, ki.StartedAt)
+	}
+	return nil
+}
+
+// checkOverlap validates specified kizami that is going to be written
+// and treats kizamis overlapped with it according to OverlapPolicy
+func (k *Kokizami) checkOverlap(ki *Kizami) error {
+	if err := validateKizami(ki); err != nil {
+		return err
+	}
+
+	ks, err := k.KizamiRepo.FindOverlapped(ki.StartedAt.UTC(), ki.StoppedAt.UTC())
+	if err != nil {
+		return err
+	}
+
+	var overlapped []*Kizami
+	for i := range ks {
+		if ks[i].ID != ki.ID && overlaps(ki, ks[i]) {
+			overlapped = append(overlapped, ks[i])
+		}
+	}
+	if len(overlapped) == 0 {
+		return nil
+	}
+
+	switch k.OverlapPolicy {
+	case OverlapReject:
+		return &OverlapError{Kizami: ki, Overlapped: overlapped}
+	case OverlapTrim:
+		return k.trim(ki, overlapped)
+	default:
+		var warned []*Kizami
+		for _, o := range overlapped {
+			if !runningTogether(ki, o) {
+				warned = append(warned, o)
+			}
+		}
+		if len(warned) != 0 && k.OnOverlap != nil {
+			k.OnOverlap(ki, warned)
+		}
+		return nil
+	}
+}
+
+// trim trims overlapped kizamis not to overlap specified kizami
+func (k *Kokizami) trim(ki *Kizami, overlapped []*Kizami) error {
+	// check all kizamis can be trimmed before modifying them
+	for _, o := range overlapped {
+		if o.StartedAt.Before(ki.StartedAt) {
+			continue
+		}
+		if isRunning(ki) || (!isRunning(o) && !o.StoppedAt.After(ki.StoppedAt)) {
+			return &OverlapError{Kizami: ki, Overlapped: []*Kizami{o}}
+		}
+	}
+
+	for _, o := range overlapped {
+		if o.StartedAt.Before(ki.StartedAt) {
+			if err := k.stop(o, ki.StartedAt.UTC()); err != nil {
+				return err
+			}
+			continue
+		}
+		startedAt := o.StartedAt
+		o.StartedAt = ki.StoppedAt.UTC()
+		if err := k.KizamiRepo.Update(o); err != nil {
+			return err
+		}
+		if err := k.fitIntervals(o, startedAt, o.StoppedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check scans all kizamis and returns conflicts,
+// that are pairs of overlapped kizamis and kizamis that have inverted range
+func (k *Kokizami) Check() ([]*Conflict, error) {
+	ks, err := k.KizamiRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*Conflict{}
+	valid := make([]*Kizami, 0, len(ks))
+	for i := range ks {
+		if validateKizami(ks[i]) != nil {
+			ret = append(ret, &Conflict{A: ks[i]})
+			continue
+		}
+		valid = append(valid, ks[i])
+	}
+
+	sort.Slice(valid, func(i, j int) bool {
+		if valid[i].StartedAt.Equal(valid[j].StartedAt) {
+			return valid[i].ID < valid[j].ID
+		}
+		return valid[i].StartedAt.Before(valid[j].StartedAt)
+	})
+
+	for i := range valid {
+		for j := i + 1; j < len(valid); j++ {
+			// valid is sorted by started_at. no more kizamis overlap valid[i]
+			if !isRunning(valid[i]) && !valid[j].StartedAt.Before(valid[i].StoppedAt) {
+				break
+			}
+			if overlaps(valid[i], valid[j]) {
+				ret = append(ret, &Conflict{A: valid[i], B: valid[j]})
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,43 @@
+package kokizami
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SearchResult represents a kizami matched by full-text search
+type SearchResult struct {
+	Kizami *Kizami
+	// Snippet is a part of desc around matched terms.
+	// Matched terms are enclosed in "[" and "]".
+	Snippet string
+	// Rank represents relevance of the kizami. Smaller is more relevant.
+	Rank float64
+}
+
+// SearchRepository is an interface to search kizamis by their desc.
+// Query follows FTS5 query syntax, that supports phrase queries
+// ("foo bar") and prefix queries (foo*).
+type SearchRepository interface {
+	Search(query string, limit int) ([]*SearchResult, error)
+}
+
+// ErrSearchUnavailable is returned by Search if SearchRepo is not available
+var ErrSearchUnavailable = errors.New("full-text search is not available. build with sqlite_fts5 tag to enable it")
+
+// Search returns kizamis whose desc matches specified query ordered by relevance.
+// All matched kizamis are returned if limit is 0.
+func (k *Kokizami) Search(query string, limit int) ([]*SearchResult, error) {
+	if k.SearchRepo == nil {
+		return nil, ErrSearchUnavailable
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query must not be empty")
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit (%d) must not be negative", limit)
+	}
+
+	return k.SearchRepo.Search(query, limit)
+}
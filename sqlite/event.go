@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"github.com/pankona/kokizami/models"
+)
+
+// EventRepo is an implementation of EventRepository using sqlite3
+type EventRepo struct {
+	db models.XODB
+}
+
+// NewEventRepo returns an implementation of EventRepository with sqlite3
+func NewEventRepo(db models.XODB) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+// FindKizamiID returns ID of the kizami imported from specified event, or 0 if not imported
+func (r *EventRepo) FindKizamiID(uid, recurrenceID string) (int, error) {
+	return models.ImportedEventKizamiID(r.db, uid, recurrenceID)
+}
+
+// Insert records that specified event is imported as the kizami
+func (r *EventRepo) Insert(uid, recurrenceID string, kizamiID int) error {
+	return models.InsertImportedEvent(r.db, uid, recurrenceID, kizamiID)
+}
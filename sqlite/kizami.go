@@ -0,0 +1,323 @@
+package sqlite
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
+)
+
+// KizamiRepo is an implementation of KizamiRepository using sqlite3
+type KizamiRepo struct {
+	db  models.XODB
+	now func() time.Time
+}
+
+// NewKizamiRepo returns an KizamiRepo
+// as an implementation of KizamiRepository
+func NewKizamiRepo(db models.XODB) *KizamiRepo {
+	return &KizamiRepo{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func initialTime() time.Time {
+	return time.Unix(0, 0).UTC()
+}
+
+func toKizami(m *models.Kizami) *kokizami.Kizami {
+	return &kokizami.Kizami{
+		ID:        m.ID,
+		Desc:      m.Desc,
+		StartedAt: m.StartedAt.Time,
+		StoppedAt: m.StoppedAt.Time,
+	}
+}
+
+func toInterval(m *models.Interval) kokizami.Interval {
+	return kokizami.Interval{
+		ID:        m.ID,
+		KizamiID:  m.KizamiID,
+		StartedAt: m.StartedAt.Time,
+		StoppedAt: m.StoppedAt.Time,
+	}
+}
+
+// intervalsByKizamiIDs returns intervals of each kizami.
+// all intervals are fetched if kizamiIDs is nil.
+func (r *KizamiRepo) intervalsByKizamiIDs(kizamiIDs []int) (map[int][]kokizami.Interval, error) {
+	var (
+		ms  []*models.Interval
+		err error
+	)
+	if kizamiIDs == nil {
+		ms, err = models.AllIntervals(r.db)
+	} else {
+		ms, err = models.IntervalsByKizamiIDs(r.db, kizamiIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[int][]kokizami.Interval{}
+	for _, m := range ms {
+		ret[m.KizamiID] = append(ret[m.KizamiID], toInterval(m))
+	}
+	for _, is := range ret {
+		sort.Slice(is, func(i, j int) bool {
+			return is[i].StartedAt.Before(is[j].StartedAt)
+		})
+	}
+
+	return ret, nil
+}
+
+// Insert inserts a kizami with specified desc
+func (r *KizamiRepo) Insert(desc string) (*kokizami.Kizami, error) {
+	m := &models.Kizami{
+		Desc:      desc,
+		StartedAt: models.SqTime(r.now().UTC()),
+		StoppedAt: models.SqTime(initialTime()),
+	}
+
+	err := m.Insert(r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return toKizami(m), nil
+}
+
+// InsertWithTime inserts a kizami with specified desc, started_at and stopped_at
+func (r *KizamiRepo) InsertWithTime(desc string, startedAt, stoppedAt time.Time) (*kokizami.Kizami, error) {
+	m := &models.Kizami{
+		Desc:      desc,
+		StartedAt: models.SqTime(startedAt),
+		StoppedAt: models.SqTime(stoppedAt),
+	}
+
+	err := m.Insert(r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return toKizami(m), nil
+}
+
+// FindAll returns all inserted kizami
+func (r *KizamiRepo) FindAll() ([]*kokizami.Kizami, error) {
+	ms, err := models.AllKizami(r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := r.intervalsByKizamiIDs(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := make([]kokizami.Kizami, len(ms))
+	for i, v := range ms {
+		ks[i].ID = v.ID
+		ks[i].Desc = v.Desc
+		ks[i].StartedAt = v.StartedAt.Time
+		ks[i].StoppedAt = v.StoppedAt.Time
+		ks[i].Intervals = is[v.ID]
+	}
+
+	ret := make([]*kokizami.Kizami, len(ms))
+	for i := range ms {
+		ret[i] = &ks[i]
+	}
+
+	return ret, nil
+}
+
+// Update updates a kizami with specified kizami
+func (r *KizamiRepo) Update(k *kokizami.Kizami) error {
+	m, err := models.KizamiByID(r.db, k.ID)
+	if err != nil {
+		return err
+	}
+
+	m.Desc = k.Desc
+	m.StartedAt = models.SqTime(k.StartedAt)
+	m.StoppedAt = models.SqTime(k.StoppedAt)
+
+	return m.Update(r.db)
+}
+
+// Delete deletes specified kizami
+func (r *KizamiRepo) Delete(k *kokizami.Kizami) error {
+	m, err := models.KizamiByID(r.db, k.ID)
+	if err != nil {
+		return err
+	}
+
+	err = models.DeleteIntervalsByKizamiID(r.db, k.ID)
+	if err != nil {
+		return err
+	}
+
+	return m.Delete(r.db)
+}
+
+// FindByID finds a kizami by specified ID
+func (r *KizamiRepo) FindByID(id int) (*kokizami.Kizami, error) {
+	m, err := models.KizamiByID(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := r.intervalsByKizamiIDs([]int{id})
+	if err != nil {
+		return nil, err
+	}
+
+	k := toKizami(m)
+	k.Intervals = is[id]
+
+	return k, nil
+}
+
+// FindByStoppedAt finds kizamis that has specified stopped at
+func (r *KizamiRepo) FindByStoppedAt(t time.Time) ([]*kokizami.Kizami, error) {
+	ms, err := models.KizamisByStoppedAt(r.db, models.SqTime(t))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toKizamisWithIntervals(ms)
+}
+
+// FindOverlapped finds kizamis that overlap specified range.
+// The range is treated as it continues forever if stoppedAt is initial value.
+func (r *KizamiRepo) FindOverlapped(startedAt, stoppedAt time.Time) ([]*kokizami.Kizami, error) {
+	ms, err := models.KizamisOverlapped(r.db, startedAt, stoppedAt, stoppedAt.Unix() == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toKizamisWithIntervals(ms)
+}
+
+// Find finds kizamis that satisfy specified query
+func (r *KizamiRepo) Find(q *kokizami.KizamiQuery) ([]*kokizami.Kizami, error) {
+	kq := &models.KizamiQuery{
+		Since:       q.Since,
+		Until:       q.Until,
+		Tags:        q.Tags,
+		ExcludeTags: q.ExcludeTags,
+		Desc:        q.Desc,
+		Running:     q.Running,
+		Now:         q.Now,
+		Descending:  q.Descending,
+		Limit:       q.Limit,
+		Offset:      q.Offset,
+	}
+
+	switch q.OrderBy {
+	case kokizami.OrderByID:
+		kq.OrderBy = models.OrderByID
+	case kokizami.OrderByStartedAt:
+		kq.OrderBy = models.OrderByStartedAt
+	case kokizami.OrderByStoppedAt:
+		kq.OrderBy = models.OrderByStoppedAt
+	case kokizami.OrderByElapsed:
+		kq.OrderBy = models.OrderByElapsed
+	case kokizami.OrderByDesc:
+		kq.OrderBy = models.OrderByDesc
+	}
+
+	ms, err := models.KizamisByQuery(r.db, kq)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toKizamisWithIntervals(ms)
+}
+
+func (r *KizamiRepo) toKizamisWithIntervals(ms []*models.Kizami) ([]*kokizami.Kizami, error) {
+	ids := make([]int, len(ms))
+	for i, v := range ms {
+		ids[i] = v.ID
+	}
+	is, err := r.intervalsByKizamiIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := make([]kokizami.Kizami, len(ms))
+	for i, v := range ms {
+		ks[i].ID = v.ID
+		ks[i].Desc = v.Desc
+		ks[i].StartedAt = v.StartedAt.Time
+		ks[i].StoppedAt = v.StoppedAt.Time
+		ks[i].Intervals = is[v.ID]
+	}
+
+	ret := make([]*kokizami.Kizami, len(ms))
+	for i := range ms {
+		ret[i] = &ks[i]
+	}
+
+	return ret, nil
+}
+
+// Tagging make relation between kizami and tags
+func (r *KizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
+	rs := models.Relations(make([]models.Relation, len(tagIDs)))
+	for i := range rs {
+		rs[i].KizamiID = kizamiID
+		rs[i].TagID = tagIDs[i]
+	}
+
+	return rs.BulkInsert(r.db)
+}
+
+// Untagging removes all tags that are held by a kizami
+func (r *KizamiRepo) Untagging(kizamiID int) error {
+	return models.DeleteRelationsByKizamiID(r.db, kizamiID)
+}
+
+// InsertInterval inserts an interval of a kizami
+func (r *KizamiRepo) InsertInterval(i *kokizami.Interval) error {
+	m := &models.Interval{
+		KizamiID:  i.KizamiID,
+		StartedAt: models.SqTime(i.StartedAt),
+		StoppedAt: models.SqTime(i.StoppedAt),
+	}
+
+	err := m.Insert(r.db)
+	if err != nil {
+		return err
+	}
+
+	i.ID = m.ID
+	return nil
+}
+
+// UpdateInterval updates an interval of a kizami
+func (r *KizamiRepo) UpdateInterval(i *kokizami.Interval) error {
+	m, err := models.IntervalByID(r.db, i.ID)
+	if err != nil {
+		return err
+	}
+
+	m.StartedAt = models.SqTime(i.StartedAt)
+	m.StoppedAt = models.SqTime(i.StoppedAt)
+
+	return m.Update(r.db)
+}
+
+// DeleteInterval deletes an interval of a kizami
+func (r *KizamiRepo) DeleteInterval(i *kokizami.Interval) error {
+	m, err := models.IntervalByID(r.db, i.ID)
+	if err != nil {
+		return err
+	}
+
+	return m.Delete(r.db)
+}
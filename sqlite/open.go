@@ -0,0 +1,141 @@
+// Package sqlite provides implementations of repositories of kokizami on sqlite3
+// and Open to get Kokizami ready on a database file.
+// It requires cgo since it depends on go-sqlite3. Programs that embed kokizami
+// without cgo can use memrepo instead.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	// go-sqlite3 is imported only here
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
+)
+
+// OpenOptions represents options to open Kokizami
+type OpenOptions struct {
+	// Now returns current time. time.Now is used if nil.
+	Now func() time.Time
+	// Location is used to bucket kizamis. time.Local is used if nil.
+	Location *time.Location
+	// OverlapPolicy and OnOverlap specify how to treat overlapped kizamis
+	OverlapPolicy kokizami.OverlapPolicy
+	OnOverlap     func(k *kokizami.Kizami, overlapped []*kokizami.Kizami)
+	// SkipMigration specifies not to migrate database and not to ensure search index on open
+	SkipMigration bool
+}
+
+// OpenOption is a function to specify an option to open Kokizami
+type OpenOption func(*OpenOptions)
+
+// WithClock specifies a function that returns current time
+func WithClock(now func() time.Time) OpenOption {
+	return func(o *OpenOptions) {
+		o.Now = now
+	}
+}
+
+// WithLocation specifies location to bucket kizamis into days, weeks and months
+func WithLocation(loc *time.Location) OpenOption {
+	return func(o *OpenOptions) {
+		o.Location = loc
+	}
+}
+
+// WithOverlapPolicy specifies how to treat a kizami that overlaps others.
+// onOverlap is called with overlapped kizamis if policy is OverlapWarn.
+func WithOverlapPolicy(policy kokizami.OverlapPolicy, onOverlap func(k *kokizami.Kizami, overlapped []*kokizami.Kizami)) OpenOption {
+	return func(o *OpenOptions) {
+		o.OverlapPolicy = policy
+		o.OnOverlap = onOverlap
+	}
+}
+
+// SkipMigration specifies not to migrate database on open.
+// Database should be migrated by models.Migrate before use.
+// Search is not available since search index is not ensured either.
+func SkipMigration() OpenOption {
+	return func(o *OpenOptions) {
+		o.SkipMigration = true
+	}
+}
+
+// DefaultPath returns the path of database that kkzm uses
+func DefaultPath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %v", err)
+	}
+	return filepath.Join(u.HomeDir, ".config", "kokizami", "db"), nil
+}
+
+// Open opens a sqlite3 database on specified path, migrates it
+// and returns Kokizami with repositories on the database.
+// Search is available only if sqlite3 is built with FTS5 (sqlite_fts5 build tag).
+// The directory of path is created if it does not exist.
+// Kokizami should be closed by Kokizami.Close after use.
+func Open(path string, opts ...OpenOption) (*kokizami.Kokizami, error) {
+	o := &OpenOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755) // #nosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory on %v: %v", dir, err)
+	}
+
+	// enable foreign keys to cascade deletion of kizamis and tags
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB: %v", err)
+	}
+
+	if !o.SkipMigration {
+		_, err = models.Migrate(db)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %v", err)
+		}
+	}
+
+	var searchRepo kokizami.SearchRepository
+	if !o.SkipMigration {
+		ok, err := models.EnsureSearchIndex(db)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to create search index: %v", err)
+		}
+		if ok {
+			searchRepo = NewSearchRepo(db)
+		}
+	}
+
+	kizamiRepo := NewKizamiRepo(db)
+	txRepo := NewTxRepo(db)
+	if o.Now != nil {
+		kizamiRepo.now = o.Now
+		txRepo.now = o.Now
+	}
+
+	return &kokizami.Kokizami{
+		Now:           o.Now,
+		DB:            db,
+		KizamiRepo:    kizamiRepo,
+		TagRepo:       NewTagRepo(db),
+		SummaryRepo:   NewSummaryRepo(db),
+		SearchRepo:    searchRepo,
+		EventRepo:     NewEventRepo(db),
+		TxRepo:        txRepo,
+		Location:      o.Location,
+		OverlapPolicy: o.OverlapPolicy,
+		OnOverlap:     o.OnOverlap,
+	}, nil
+}
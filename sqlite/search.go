@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
+)
+
+// SearchRepo is an implementation of SearchRepository using FTS5 of sqlite3
+type SearchRepo struct {
+	db      models.XODB
+	kizamis *KizamiRepo
+}
+
+// NewSearchRepo returns a struct that implements SearchRepository with sqlite3.
+// The search index must be ensured by models.EnsureSearchIndex.
+func NewSearchRepo(db models.XODB) *SearchRepo {
+	return &SearchRepo{
+		db:      db,
+		kizamis: NewKizamiRepo(db),
+	}
+}
+
+// Search returns kizamis whose desc matches specified query ordered by relevance
+func (r *SearchRepo) Search(query string, limit int) ([]*kokizami.SearchResult, error) {
+	hs, err := models.KizamisBySearch(r.db, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search [%s]: %v", query, err)
+	}
+
+	ms := make([]*models.Kizami, len(hs))
+	for i := range hs {
+		ms[i] = hs[i].Kizami
+	}
+	ks, err := r.kizamis.toKizamisWithIntervals(ms)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*kokizami.SearchResult, len(hs))
+	for i := range hs {
+		ret[i] = &kokizami.SearchResult{
+			Kizami:  ks[i],
+			Snippet: hs[i].Snippet,
+			Rank:    hs[i].Rank,
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,235 @@
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/kokizamitest"
+	"github.com/pankona/kokizami/sqlite"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	kokizamitest.TestRepositories(t, func(t *testing.T) (*kokizamitest.Repositories, func()) {
+		dir, err := ioutil.TempDir("", "kkzm")
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		k, err := sqlite.Open(filepath.Join(dir, "db"))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+
+		return &kokizamitest.Repositories{
+				KizamiRepo:  k.KizamiRepo,
+				TagRepo:     k.TagRepo,
+				SummaryRepo: k.SummaryRepo,
+				TxRepo:      k.TxRepo,
+				EventRepo:   k.EventRepo,
+			}, func() {
+				_ = k.Close()
+				_ = os.RemoveAll(dir)
+			}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	now := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+	loc := time.FixedZone("JST", 9*60*60)
+
+	// directory is created if not exist
+	path := filepath.Join(dir, "kokizami", "db")
+	k, err := sqlite.Open(path,
+		sqlite.WithClock(func() time.Time { return now }),
+		sqlite.WithLocation(loc),
+		sqlite.WithOverlapPolicy(kokizami.OverlapReject, nil))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ki, err := k.Start("hoge #a")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ki.StartedAt.Equal(now) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ki.StartedAt, now)
+	}
+
+	_, err = k.Add("fuga", now.Add(-time.Hour), now.Add(time.Hour))
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	// repositories in transactions use the clock as well
+	err = k.Transaction(func(tx *kokizami.Kokizami) error {
+		ki, err := tx.KizamiRepo.Insert("piyo")
+		if err != nil {
+			return err
+		}
+		if !ki.StartedAt.Equal(now) {
+			t.Fatalf("unexpected result: [got] %v [want] %v", ki.StartedAt, now)
+		}
+		return tx.KizamiRepo.Delete(ki)
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	from, _ := k.Range(kokizami.PeriodDay, now)
+	if want := time.Date(2019, 5, 1, 0, 0, 0, 0, loc); !from.Equal(want) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", from, want)
+	}
+
+	err = k.Close()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// data persists over reopening
+	k, err = sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = k.Close() }()
+
+	ts, err := k.TagsByKizamiID(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ts) != 1 || ts[0].Label != "#a" {
+		t.Fatalf("unexpected result: [got] %v [want] [#a]", ts)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "db")
+	k, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = k.Close() }()
+
+	if k.SearchRepo == nil {
+		_, err = k.Search("hoge", 0)
+		if err != kokizami.ErrSearchUnavailable {
+			t.Fatalf("unexpected result: [got] %v [want] %v", err, kokizami.ErrSearchUnavailable)
+		}
+		t.Skip("FTS5 is not available. run tests with -tags sqlite_fts5")
+	}
+
+	base := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+	var ids []int
+	for i, v := range []string{
+		"migrate database to new schema #db",
+		"review migration plan",
+		"lunch",
+		"write database migration #db",
+	} {
+		ki, err := k.Add(v, base.Add(time.Duration(i)*time.Hour), base.Add(time.Duration(i)*time.Hour+30*time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		ids = append(ids, ki.ID)
+	}
+
+	// desc is indexed on update and removed from index on deletion
+	ki, err := k.Get(ids[2])
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki.Desc = "lunch with database team"
+	_, err = k.Edit(ki)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Delete(ids[1])
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		in   string
+		want []int
+	}{
+		{in: "lunch", want: []int{ids[2]}},
+		{in: "review", want: []int{}},
+		{in: "migrat*", want: []int{ids[3], ids[0]}},
+		{in: `"database migration"`, want: []int{ids[3]}},
+		{in: "#db", want: []int{ids[3], ids[0]}},
+		{in: "database NOT #db", want: []int{ids[2]}},
+	}
+
+	for i, tc := range tcs {
+		rs, err := k.Search(tc.in, 0)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		got := []int{}
+		for _, r := range rs {
+			got = append(got, r.Kizami.ID)
+		}
+		if diff := cmp.Diff(got, tc.want, cmpopts.SortSlices(func(a, b int) bool { return a < b })); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	rs, err := k.Search(`"database team"`, 1)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] 1 result", rs)
+	}
+	if diff := cmp.Diff(rs[0].Snippet, "lunch with [database team]"); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	_, err = k.Search(`"unterminated`, 0)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	// index is rebuilt on open if triggers are missing
+	_, err = k.DB.Exec("DROP TRIGGER kizami_fts_ai")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = k.Start("forgotten task")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Close()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	k, err = sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	rs, err = k.Search("forgotten", 0)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] 1 result", rs)
+	}
+}
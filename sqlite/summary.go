@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/models"
+)
+
+// SummaryRepo is an implementation of SummaryRepository
+type SummaryRepo struct {
+	db models.XODB
+}
+
+// NewSummaryRepo returns a struct that implements SummaryRepository with sqlite3
+func NewSummaryRepo(db models.XODB) *SummaryRepo {
+	return &SummaryRepo{db: db}
+}
+
+func toElapsedQuery(from, to time.Time, opts *kokizami.SummaryOptions) *models.ElapsedQuery {
+	eq := &models.ElapsedQuery{
+		From:           from,
+		To:             to,
+		IncludeRunning: opts.IncludeRunning,
+		Now:            opts.Now,
+		Depth:          opts.Depth,
+	}
+
+	switch opts.Attribution {
+	case kokizami.AttributionFull:
+		eq.Attribution = models.AttributionFull
+	case kokizami.AttributionSplit:
+		eq.Attribution = models.AttributionSplit
+	case kokizami.AttributionPrimary:
+		eq.Attribution = models.AttributionPrimary
+	}
+
+	return eq
+}
+
+// ElapsedByDesc returns an array of Elapsed time in specified range to summarize them by desc.
+// Each kizami is counted only once regardless of Attribution, and its primary tag is returned as Tag.
+func (r *SummaryRepo) ElapsedByDesc(from, to time.Time, opts *kokizami.SummaryOptions) ([]*kokizami.Elapsed, error) {
+	ms, err := models.ElapsedByDesc(r.db, toElapsedQuery(from, to, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]kokizami.Elapsed, len(ms))
+	for i := range ms {
+		es[i].Tag = ms[i].Tag
+		es[i].Desc = ms[i].Desc
+		es[i].Count = ms[i].Count
+		es[i].Elapsed = ms[i].Elapsed
+		es[i].InProgress = ms[i].InProgress
+	}
+
+	ret := make([]*kokizami.Elapsed, len(es))
+	for i := range es {
+		ret[i] = &es[i]
+	}
+
+	return ret, nil
+}
+
+// ElapsedByTag returns an array of Elapsed time in specified range to summarize them by tag
+func (r *SummaryRepo) ElapsedByTag(from, to time.Time, opts *kokizami.SummaryOptions) ([]*kokizami.Elapsed, error) {
+	ms, err := models.ElapsedByTag(r.db, toElapsedQuery(from, to, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]kokizami.Elapsed, len(ms))
+	for i := range ms {
+		es[i].Tag = ms[i].Tag
+		es[i].Desc = ms[i].Desc
+		es[i].Count = ms[i].Count
+		es[i].Elapsed = ms[i].Elapsed
+		es[i].InProgress = ms[i].InProgress
+	}
+
+	ret := make([]*kokizami.Elapsed, len(es))
+	for i := range es {
+		ret[i] = &es[i]
+	}
+
+	return ret, nil
+}
+
+// ElapsedTotal returns total Elapsed time in specified range
+func (r *SummaryRepo) ElapsedTotal(from, to time.Time, opts *kokizami.SummaryOptions) (*kokizami.Elapsed, error) {
+	m, err := models.ElapsedTotal(r.db, toElapsedQuery(from, to, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return &kokizami.Elapsed{
+		Count:      m.Count,
+		Elapsed:    m.Elapsed,
+		InProgress: m.InProgress,
+	}, nil
+}
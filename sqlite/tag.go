@@ -1,19 +1,17 @@
-package repo
+package sqlite
 
 import (
-	"database/sql"
-
 	"github.com/pankona/kokizami"
 	"github.com/pankona/kokizami/models"
 )
 
 // TagRepo is an implementation of TagRepository
 type TagRepo struct {
-	db *sql.DB
+	db models.XODB
 }
 
 // NewTagRepo returns an implementation of TagRepository with sqlite3
-func NewTagRepo(db *sql.DB) *TagRepo {
+func NewTagRepo(db models.XODB) *TagRepo {
 	return &TagRepo{db: db}
 }
 
@@ -81,6 +79,25 @@ func (t *TagRepo) FindByKizamiID(kizamiID int) ([]*kokizami.Tag, error) {
 	return ret, nil
 }
 
+// FindByKizamiIDs returns tags they are held by each of specified kizamis
+func (t *TagRepo) FindByKizamiIDs(kizamiIDs []int) (map[int][]*kokizami.Tag, error) {
+	ms, err := models.TagsByKizamiIDs(t.db, kizamiIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int][]*kokizami.Tag, len(ms))
+	for kizamiID, vs := range ms {
+		tags := make([]*kokizami.Tag, len(vs))
+		for i, v := range vs {
+			tags[i] = &kokizami.Tag{ID: v.ID, Label: v.Label}
+		}
+		ret[kizamiID] = tags
+	}
+
+	return ret, nil
+}
+
 // FindByLabels finds tags by specified labels
 func (t *TagRepo) FindByLabels(labels []string) ([]*kokizami.Tag, error) {
 	ms, err := models.TagsByLabels(t.db, labels)
@@ -98,15 +115,14 @@ func (t *TagRepo) FindByLabels(labels []string) ([]*kokizami.Tag, error) {
 
 // Insert inserts tags with specified labels
 func (t *TagRepo) Insert(labels []string) error {
-	ts := models.Tags(make([]models.Tag, len(labels)))
+	ts := models.Tags(make([]models.Tag, 0, len(labels)))
 
-	for i := range ts {
+	for i := range labels {
 		// skip empty string
 		if len(labels[i]) == 0 {
-			ts = ts[:len(ts)-1]
 			continue
 		}
-		ts[i].Label = labels[i]
+		ts = append(ts, models.Tag{Label: labels[i]})
 	}
 
 	return ts.BulkInsert(t.db)
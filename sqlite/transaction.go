@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// TxRepo is an implementation of TransactionRepository using sqlite3
+type TxRepo struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewTxRepo returns an implementation of TransactionRepository with sqlite3
+func NewTxRepo(db *sql.DB) *TxRepo {
+	return &TxRepo{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Begin begins a transaction
+func (r *TxRepo) Begin() (kokizami.Transaction, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{tx: tx, now: r.now}, nil
+}
+
+type transaction struct {
+	tx  *sql.Tx
+	now func() time.Time
+}
+
+// KizamiRepo returns KizamiRepository that operates in the transaction
+// with the clock of TxRepo
+func (t *transaction) KizamiRepo() kokizami.KizamiRepository {
+	r := NewKizamiRepo(t.tx)
+	r.now = t.now
+	return r
+}
+
+// TagRepo returns TagRepository that operates in the transaction
+func (t *transaction) TagRepo() kokizami.TagRepository {
+	return NewTagRepo(t.tx)
+}
+
+// SummaryRepo returns SummaryRepository that operates in the transaction
+func (t *transaction) SummaryRepo() kokizami.SummaryRepository {
+	return NewSummaryRepo(t.tx)
+}
+
+// SearchRepo returns SearchRepository that operates in the transaction.
+// It should be used only if search index is available.
+func (t *transaction) SearchRepo() kokizami.SearchRepository {
+	return NewSearchRepo(t.tx)
+}
+
+// EventRepo returns EventRepository that operates in the transaction
+func (t *transaction) EventRepo() kokizami.EventRepository {
+	return NewEventRepo(t.tx)
+}
+
+// Commit commits the transaction
+func (t *transaction) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
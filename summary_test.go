@@ -0,0 +1,155 @@
+package kokizami_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata" // for DST tests with America/New_York
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/sqlite"
+)
+
+// setupSQLite returns Kokizami on a sqlite3 database in a temporary directory
+// and a function to remove it
+func setupSQLite(t *testing.T) (*kokizami.Kokizami, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "kkzm")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	k, err := sqlite.Open(filepath.Join(dir, "db"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	return k, func() {
+		_ = k.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestSummaryByTagPerPeriod(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		name        string
+		inLocation  *time.Location
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		inFrom      time.Time
+		inTo        time.Time
+		inPeriod    kokizami.Period
+		wantElapsed []time.Duration
+	}{
+		{
+			name:        "midnight",
+			inLocation:  time.UTC,
+			inStartedAt: time.Date(2019, 1, 30, 23, 0, 0, 0, time.UTC),
+			inStoppedAt: time.Date(2019, 1, 31, 2, 0, 0, 0, time.UTC),
+			inFrom:      time.Date(2019, 1, 30, 0, 0, 0, 0, time.UTC),
+			inTo:        time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC),
+			inPeriod:    kokizami.PeriodDay,
+			wantElapsed: []time.Duration{1 * time.Hour, 2 * time.Hour},
+		},
+		{
+			name:        "week end",
+			inLocation:  time.UTC,
+			inStartedAt: time.Date(2019, 1, 6, 22, 0, 0, 0, time.UTC),
+			inStoppedAt: time.Date(2019, 1, 7, 1, 0, 0, 0, time.UTC),
+			inFrom:      time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+			inTo:        time.Date(2019, 1, 14, 0, 0, 0, 0, time.UTC),
+			inPeriod:    kokizami.PeriodWeek,
+			wantElapsed: []time.Duration{2 * time.Hour, 1 * time.Hour},
+		},
+		{
+			name:        "month end",
+			inLocation:  time.UTC,
+			inStartedAt: time.Date(2019, 1, 31, 23, 0, 0, 0, time.UTC),
+			inStoppedAt: time.Date(2019, 2, 1, 2, 0, 0, 0, time.UTC),
+			inFrom:      time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+			inTo:        time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+			inPeriod:    kokizami.PeriodMonth,
+			wantElapsed: []time.Duration{1 * time.Hour, 2 * time.Hour},
+		},
+		{
+			name:        "range is clipped",
+			inLocation:  time.UTC,
+			inStartedAt: time.Date(2019, 1, 31, 23, 0, 0, 0, time.UTC),
+			inStoppedAt: time.Date(2019, 2, 1, 2, 0, 0, 0, time.UTC),
+			inFrom:      time.Date(2019, 1, 31, 23, 30, 0, 0, time.UTC),
+			inTo:        time.Date(2019, 2, 1, 1, 0, 0, 0, time.UTC),
+			inPeriod:    kokizami.PeriodMonth,
+			wantElapsed: []time.Duration{30 * time.Minute, 1 * time.Hour},
+		},
+		{
+			// 2019-03-10 has only 23 hours in New York
+			name:        "DST starts",
+			inLocation:  ny,
+			inStartedAt: time.Date(2019, 3, 9, 22, 0, 0, 0, ny),
+			inStoppedAt: time.Date(2019, 3, 11, 2, 0, 0, 0, ny),
+			inFrom:      time.Date(2019, 3, 9, 0, 0, 0, 0, ny),
+			inTo:        time.Date(2019, 3, 12, 0, 0, 0, 0, ny),
+			inPeriod:    kokizami.PeriodDay,
+			wantElapsed: []time.Duration{2 * time.Hour, 23 * time.Hour, 2 * time.Hour},
+		},
+		{
+			// 2019-11-03 has 25 hours in New York
+			name:        "DST ends",
+			inLocation:  ny,
+			inStartedAt: time.Date(2019, 11, 2, 22, 0, 0, 0, ny),
+			inStoppedAt: time.Date(2019, 11, 4, 2, 0, 0, 0, ny),
+			inFrom:      time.Date(2019, 11, 2, 0, 0, 0, 0, ny),
+			inTo:        time.Date(2019, 11, 5, 0, 0, 0, 0, ny),
+			inPeriod:    kokizami.PeriodDay,
+			wantElapsed: []time.Duration{2 * time.Hour, 25 * time.Hour, 2 * time.Hour},
+		},
+	}
+
+	// clipping is done by SQL in sqlite implementation
+	backends := []struct {
+		name  string
+		setup func(t *testing.T) (*kokizami.Kokizami, func())
+	}{
+		{name: "memrepo", setup: func(t *testing.T) (*kokizami.Kokizami, func()) { return newMemKokizami(), func() {} }},
+		{name: "sqlite", setup: setupSQLite},
+	}
+
+	for _, b := range backends {
+		for _, tc := range tcs {
+			k, teardown := b.setup(t)
+			k.Location = tc.inLocation
+
+			_, err := k.Add("hoge #a", tc.inStartedAt, tc.inStoppedAt)
+			if err != nil {
+				teardown()
+				t.Fatalf("[%s/%s] unexpected result: [got] %v [want] nil", b.name, tc.name, err)
+			}
+
+			ps, err := k.SummaryByTagPerPeriod(tc.inFrom, tc.inTo, tc.inPeriod)
+			teardown()
+			if err != nil {
+				t.Fatalf("[%s/%s] unexpected result: [got] %v [want] nil", b.name, tc.name, err)
+			}
+
+			got := make([]time.Duration, len(ps))
+			for i := range ps {
+				for _, e := range ps[i].Elapsed {
+					got[i] += e.Elapsed
+				}
+			}
+
+			if diff := cmp.Diff(got, tc.wantElapsed); diff != "" {
+				t.Fatalf("[%s/%s] unexpected result: (-got +want) %s", b.name, tc.name, diff)
+			}
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package kokizami
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Tag represents a tag
 type Tag struct {
 	ID    int
@@ -14,7 +20,131 @@ type TagRepository interface {
 	FindByID(id int) (*Tag, error)
 	FindAll() ([]*Tag, error)
 	FindByKizamiID(kizamiID int) ([]*Tag, error)
+	FindByKizamiIDs(kizamiIDs []int) (map[int][]*Tag, error)
 	FindByLabels(labels []string) ([]*Tag, error)
 	Insert(labels []string) error
 	Delete(id int) error
 }
+
+// ExtractTags returns labels of tags contained in specified desc.
+// A tag is a word that starts with "#".
+func ExtractTags(desc string) []string {
+	var tags []string
+	for _, v := range strings.Split(desc, " ") {
+		if strings.HasPrefix(v, "#") && len(v) >= 2 {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
+
+// ToTag returns label of a tag named name, that is used to convert tags or
+// projects of other tools into tags. Spaces are replaced with "_" since a tag is a word.
+// Empty string is returned if name has no word.
+func ToTag(name string) string {
+	name = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(name), "#")), "_")
+	if name == "" {
+		return ""
+	}
+	return "#" + name
+}
+
+// Untitled is desc of kizamis imported from other tools that have neither text nor tags
+const Untitled = "(untitled)"
+
+// ComposeDesc returns desc of a kizami imported from other tools, that is words of text
+// followed by tags named names. Names are converted by ToTag, and the second and later
+// occurrences of each tag are removed. Untitled is returned if desc would be empty.
+func ComposeDesc(text string, names []string) string {
+	words := strings.Fields(text)
+	for _, v := range names {
+		if l := ToTag(v); l != "" {
+			words = append(words, l)
+		}
+	}
+
+	ret := []string{}
+	seen := map[string]bool{}
+	for _, v := range words {
+		if len(ExtractTags(v)) != 0 {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+		}
+		ret = append(ret, v)
+	}
+
+	if len(ret) == 0 {
+		return Untitled
+	}
+	return strings.Join(ret, " ")
+}
+
+// TagSeparator separates segments of hierarchical tags, e.g. #client/acme/backend
+const TagSeparator = "/"
+
+// SplitTag returns segments of a hierarchical tag without "#".
+// e.g. "#client/acme/backend" is split into ["client", "acme", "backend"].
+func SplitTag(label string) []string {
+	return strings.Split(strings.TrimPrefix(label, "#"), TagSeparator)
+}
+
+// TagAtDepth returns an ancestor of a tag that has depth segments.
+// The tag itself is returned if it is not deeper than depth or depth is not positive.
+// e.g. TagAtDepth("#client/acme/backend", 2) returns "#client/acme".
+func TagAtDepth(label string, depth int) string {
+	ss := SplitTag(label)
+	if depth <= 0 || len(ss) <= depth {
+		return label
+	}
+	return "#" + strings.Join(ss[:depth], TagSeparator)
+}
+
+// IsSubtag returns true if label is parent or its descendant.
+// e.g. "#client/acme" is a subtag of "#client".
+func IsSubtag(label, parent string) bool {
+	return label == parent || strings.HasPrefix(label, parent+TagSeparator)
+}
+
+// TagUsage represents how much a tag is used
+type TagUsage struct {
+	Tag Tag
+	// Count is the number of kizamis tagged with the tag
+	Count int
+	// Elapsed is total elapsed time of the kizamis.
+	// On-going kizamis are counted up to now.
+	Elapsed time.Duration
+}
+
+// validateLabel returns error if label is not a tag that ExtractTags extracts
+func validateLabel(label string) error {
+	ls := ExtractTags(label)
+	if len(ls) != 1 || ls[0] != label {
+		return fmt.Errorf("invalid tag [%s]. tag must start with # and must not contain spaces", label)
+	}
+	return nil
+}
+
+// rewriteTag replaces words labeled one of from in desc with to.
+// If to is a tag, the second and later occurrences of it are removed.
+func rewriteTag(desc string, from []string, to string) string {
+	ws := strings.Split(desc, " ")
+	ret := make([]string, 0, len(ws))
+	found := false
+	for _, w := range ws {
+		for _, v := range from {
+			if w == v {
+				w = to
+			}
+		}
+		if w == to && strings.HasPrefix(to, "#") {
+			if found {
+				continue
+			}
+			found = true
+		}
+		ret = append(ret, w)
+	}
+	return strings.Join(ret, " ")
+}
@@ -0,0 +1,243 @@
+package kokizami_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/memrepo"
+)
+
+func newMemKokizami() *kokizami.Kokizami {
+	db := memrepo.NewDB()
+	return &kokizami.Kokizami{
+		KizamiRepo:  memrepo.NewKizamiRepo(db),
+		TagRepo:     memrepo.NewTagRepo(db),
+		SummaryRepo: memrepo.NewSummaryRepo(db),
+		TxRepo:      memrepo.NewTxRepo(db),
+		EventRepo:   memrepo.NewEventRepo(db),
+	}
+}
+
+func descs(t *testing.T, k *kokizami.Kokizami) []string {
+	t.Helper()
+	ks, err := k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ret := make([]string, len(ks))
+	for i := range ks {
+		ret[i] = ks[i].Desc
+	}
+	return ret
+}
+
+func tagLabels(t *testing.T, k *kokizami.Kokizami) []string {
+	t.Helper()
+	ts, err := k.Tags()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ret := make([]string, len(ts))
+	for i := range ts {
+		ret[i] = ts[i].Label
+	}
+	return ret
+}
+
+func TestManageTags(t *testing.T) {
+	base := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		in        func(k *kokizami.Kokizami) error
+		wantErr   bool
+		wantDescs []string
+		wantTags  []string
+	}{
+		{
+			in:        func(k *kokizami.Kokizami) error { return k.RenameTag("#dev", "#code") },
+			wantDescs: []string{"write #doc", "review #code #go", "#code #go"},
+			wantTags:  []string{"#doc", "#go", "#code"},
+		},
+		{
+			// the merged tag appears only once in desc
+			in:        func(k *kokizami.Kokizami) error { return k.RenameTag("#dev", "#go") },
+			wantDescs: []string{"write #doc", "review #go", "#go"},
+			wantTags:  []string{"#doc", "#go"},
+		},
+		{
+			in:        func(k *kokizami.Kokizami) error { return k.MergeTags([]string{"#doc", "#go"}, "#work") },
+			wantDescs: []string{"write #work", "review #dev #work", "#dev #work"},
+			wantTags:  []string{"#dev", "#work"},
+		},
+		{
+			in:        func(k *kokizami.Kokizami) error { return k.RemoveTag("#dev") },
+			wantDescs: []string{"write #doc", "review dev #go", "dev #go"},
+			wantTags:  []string{"#doc", "#go"},
+		},
+		{
+			in:      func(k *kokizami.Kokizami) error { return k.RenameTag("#unknown", "#code") },
+			wantErr: true,
+		},
+		{
+			in:      func(k *kokizami.Kokizami) error { return k.RenameTag("#dev", "code") },
+			wantErr: true,
+		},
+		{
+			// nothing is changed if any of tags is failed
+			in:      func(k *kokizami.Kokizami) error { return k.MergeTags([]string{"#doc", "#unknown"}, "#work") },
+			wantErr: true,
+		},
+		{
+			in:      func(k *kokizami.Kokizami) error { return k.RemoveTag("#unknown") },
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tcs {
+		k := newMemKokizami()
+		for j, v := range []string{"write #doc", "review #dev #go", "#dev #go"} {
+			_, err := k.Add(v, base.Add(time.Duration(j)*time.Hour), base.Add(time.Duration(j)*time.Hour+time.Minute))
+			if err != nil {
+				t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+			}
+		}
+
+		err := tc.in(k)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			tc.wantDescs = []string{"write #doc", "review #dev #go", "#dev #go"}
+			tc.wantTags = []string{"#doc", "#dev", "#go"}
+		} else if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		if diff := cmp.Diff(descs(t, k), tc.wantDescs); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(tagLabels(t, k), tc.wantTags); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestTagUsages(t *testing.T) {
+	base := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+	k := newMemKokizami()
+
+	_, err := k.Add("write #doc", base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = k.Add("review #doc #code", base.Add(time.Hour), base.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.AddTags([]string{"#unused"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	us, err := k.TagUsages()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	got := map[string]kokizami.TagUsage{}
+	for _, v := range us {
+		got[v.Tag.Label] = kokizami.TagUsage{Count: v.Count, Elapsed: v.Elapsed}
+	}
+	want := map[string]kokizami.TagUsage{
+		"#doc":    {Count: 2, Elapsed: 90 * time.Minute},
+		"#code":   {Count: 1, Elapsed: 30 * time.Minute},
+		"#unused": {},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestTagAtDepth(t *testing.T) {
+	tcs := []struct {
+		inLabel string
+		inDepth int
+		want    string
+	}{
+		{inLabel: "#client/acme/backend", inDepth: 1, want: "#client"},
+		{inLabel: "#client/acme/backend", inDepth: 2, want: "#client/acme"},
+		{inLabel: "#client/acme/backend", inDepth: 3, want: "#client/acme/backend"},
+		{inLabel: "#client/acme/backend", inDepth: 0, want: "#client/acme/backend"},
+		{inLabel: "#client", inDepth: 2, want: "#client"},
+	}
+
+	for i, tc := range tcs {
+		got := kokizami.TagAtDepth(tc.inLabel, tc.inDepth)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestIsSubtag(t *testing.T) {
+	tcs := []struct {
+		inLabel  string
+		inParent string
+		want     bool
+	}{
+		{inLabel: "#client", inParent: "#client", want: true},
+		{inLabel: "#client/acme", inParent: "#client", want: true},
+		{inLabel: "#clients", inParent: "#client", want: false},
+		{inLabel: "#client", inParent: "#client/acme", want: false},
+	}
+
+	for i, tc := range tcs {
+		got := kokizami.IsSubtag(tc.inLabel, tc.inParent)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestToTag(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want string
+	}{
+		{in: "code", want: "#code"},
+		{in: "#code", want: "#code"},
+		{in: " client  acme ", want: "#client_acme"},
+		{in: "client/acme", want: "#client/acme"},
+		{in: " ", want: ""},
+		{in: "#", want: ""},
+	}
+
+	for i, tc := range tcs {
+		got := kokizami.ToTag(tc.in)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestComposeDesc(t *testing.T) {
+	tcs := []struct {
+		inText  string
+		inNames []string
+		want    string
+	}{
+		{inText: "review", inNames: []string{"code"}, want: "review #code"},
+		{inText: "review #code #code", inNames: []string{"#code", "client acme", "client acme"}, want: "review #code #client_acme"},
+		{inText: " multi  line\n", inNames: []string{" ", ""}, want: "multi line"},
+		{inText: "", inNames: []string{"code"}, want: "#code"},
+		{inText: " ", inNames: nil, want: kokizami.Untitled},
+	}
+
+	for i, tc := range tcs {
+		got := kokizami.ComposeDesc(tc.inText, tc.inNames)
+		if got != tc.want {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package kokizami
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	dateTimeLayouts = []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	}
+
+	clockLayouts = []string{
+		"15:04:05",
+		"15:04",
+	}
+
+	weekdays = map[string]time.Weekday{
+		"sun": time.Sunday, "sunday": time.Sunday,
+		"mon": time.Monday, "monday": time.Monday,
+		"tue": time.Tuesday, "tuesday": time.Tuesday,
+		"wed": time.Wednesday, "wednesday": time.Wednesday,
+		"thu": time.Thursday, "thursday": time.Thursday,
+		"fri": time.Friday, "friday": time.Friday,
+		"sat": time.Saturday, "saturday": time.Saturday,
+	}
+)
+
+// ParseTime parses a time expression relative to now.
+// The expression is interpreted in now's location.
+// Following forms are supported.
+//
+//	now                   ... now
+//	-15m, +1h30m          ... relative duration from now
+//	15m ago               ... same as -15m
+//	9:30, 17:00:15        ... specified time of today
+//	today 9:30            ... same as above
+//	yesterday 17:00       ... specified time of yesterday
+//	tomorrow 9:00         ... specified time of tomorrow
+//	mon 10:00, friday     ... specified time of the latest weekday on or before today
+//	2006-01-02 15:04[:05] ... absolute date and time
+//	2006-01-02            ... midnight of specified date
+//	RFC3339               ... e.g. 2006-01-02T15:04:05+09:00
+//
+// Time of day can be omitted with day words, and midnight is used then.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	raw := strings.TrimSpace(s)
+	expr := strings.ToLower(raw)
+	loc := now.Location()
+
+	switch {
+	case expr == "":
+		return time.Time{}, fmt.Errorf("time expression must not be empty")
+	case expr == "now":
+		return now, nil
+	case strings.HasPrefix(expr, "-") || strings.HasPrefix(expr, "+"):
+		d, err := time.ParseDuration(expr)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time [%s]: %v", s, err)
+		}
+		return now.Add(d), nil
+	case strings.HasSuffix(expr, " ago"):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimSuffix(expr, " ago")))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time [%s]: %v", s, err)
+		}
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), nil
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	// [day] [clock]
+	day, clock := "today", expr
+	if fs := strings.Fields(expr); len(fs) == 2 {
+		day, clock = fs[0], fs[1]
+	} else if len(fs) == 1 && !strings.Contains(expr, ":") {
+		day, clock = fs[0], ""
+	} else if len(fs) > 2 {
+		return time.Time{}, fmt.Errorf("invalid time expression [%s]", s)
+	}
+
+	y, m, d := now.In(loc).Date()
+	base := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+	switch day {
+	case "today":
+	case "yesterday":
+		base = base.AddDate(0, 0, -1)
+	case "tomorrow":
+		base = base.AddDate(0, 0, 1)
+	default:
+		wd, ok := weekdays[day]
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid time expression [%s]", s)
+		}
+		offset := (int(base.Weekday()) - int(wd) + 7) % 7
+		base = base.AddDate(0, 0, -offset)
+	}
+
+	if clock == "" {
+		return base, nil
+	}
+
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, clock); err == nil {
+			y, m, d := base.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time expression [%s]", s)
+}
@@ -0,0 +1,67 @@
+package kokizami
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	// 2019-05-08 is Wednesday
+	now := time.Date(2019, 5, 8, 12, 34, 56, 0, jst)
+
+	tcs := []struct {
+		in      string
+		wantErr bool
+		want    time.Time
+	}{
+		{in: "now", want: now},
+		{in: " NOW ", want: now},
+		{in: "-15m", want: now.Add(-15 * time.Minute)},
+		{in: "+1h30m", want: now.Add(90 * time.Minute)},
+		{in: "-1h", want: now.Add(-time.Hour)},
+		{in: "15m ago", want: now.Add(-15 * time.Minute)},
+		{in: "1h30m ago", want: now.Add(-90 * time.Minute)},
+		{in: "9:30", want: time.Date(2019, 5, 8, 9, 30, 0, 0, jst)},
+		{in: "09:30", want: time.Date(2019, 5, 8, 9, 30, 0, 0, jst)},
+		{in: "17:00:15", want: time.Date(2019, 5, 8, 17, 0, 15, 0, jst)},
+		{in: "today 9:30", want: time.Date(2019, 5, 8, 9, 30, 0, 0, jst)},
+		{in: "today", want: time.Date(2019, 5, 8, 0, 0, 0, 0, jst)},
+		{in: "yesterday 17:00", want: time.Date(2019, 5, 7, 17, 0, 0, 0, jst)},
+		{in: "Yesterday", want: time.Date(2019, 5, 7, 0, 0, 0, 0, jst)},
+		{in: "tomorrow 9:00", want: time.Date(2019, 5, 9, 9, 0, 0, 0, jst)},
+		{in: "mon 10:00", want: time.Date(2019, 5, 6, 10, 0, 0, 0, jst)},
+		{in: "monday 10:00", want: time.Date(2019, 5, 6, 10, 0, 0, 0, jst)},
+		{in: "wed 10:00", want: time.Date(2019, 5, 8, 10, 0, 0, 0, jst)},
+		{in: "thu 10:00", want: time.Date(2019, 5, 2, 10, 0, 0, 0, jst)},
+		{in: "sun", want: time.Date(2019, 5, 5, 0, 0, 0, 0, jst)},
+		{in: "2019-04-30 23:59:59", want: time.Date(2019, 4, 30, 23, 59, 59, 0, jst)},
+		{in: "2019-04-30 23:59", want: time.Date(2019, 4, 30, 23, 59, 0, 0, jst)},
+		{in: "2019-04-30T23:59", want: time.Date(2019, 4, 30, 23, 59, 0, 0, jst)},
+		{in: "2019-04-30", want: time.Date(2019, 4, 30, 0, 0, 0, 0, jst)},
+		{in: "2019-04-30T14:59:00Z", want: time.Date(2019, 4, 30, 23, 59, 0, 0, jst)},
+		{in: "", wantErr: true},
+		{in: "-15", wantErr: true},
+		{in: "soon ago", wantErr: true},
+		{in: "25:00", wantErr: true},
+		{in: "someday 10:00", wantErr: true},
+		{in: "mon 10:00 extra", wantErr: true},
+		{in: "2019-13-01 10:00", wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		ret, err := ParseTime(tc.in, now)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] %v [want] some error", i, ret)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if !ret.Equal(tc.want) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, ret, tc.want)
+		}
+	}
+}
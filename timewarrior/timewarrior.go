@@ -0,0 +1,348 @@
+// Package timewarrior converts intervals of Timewarrior from and to kokizami.
+//
+// Timewarrior stores intervals in data files named YYYY-MM.data, one interval per line:
+//
+//	inc 20190501T090000Z - 20190501T100000Z # code "client acme" # "review"
+//
+// Tags of an interval are mapped to #tags in desc of a kizami and
+// its annotation is mapped to the rest of the desc.
+package timewarrior
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// timeFormat is the format of time in data files
+const timeFormat = "20060102T150405Z"
+
+// dataFile matches names of data files of intervals
+var dataFile = regexp.MustCompile(`^\d{4}-\d{2}\.data$`)
+
+// Interval represents an interval of Timewarrior
+type Interval struct {
+	Start time.Time
+	// End is zero if the interval is open
+	End        time.Time
+	Tags       []string
+	Annotation string
+}
+
+// tokenize splits a line into words. Quoted words are unquoted.
+// quoted reports whether each word was quoted.
+func tokenize(line string) (words []string, quoted []bool, err error) {
+	rs := []rune(line)
+	for i := 0; i < len(rs); i++ {
+		if rs[i] == ' ' {
+			continue
+		}
+
+		if rs[i] != '"' {
+			j := i
+			for j < len(rs) && rs[j] != ' ' {
+				j++
+			}
+			words = append(words, string(rs[i:j]))
+			quoted = append(quoted, false)
+			i = j
+			continue
+		}
+
+		var b strings.Builder
+		closed := false
+		for i++; i < len(rs); i++ {
+			if rs[i] == '\\' && i+1 < len(rs) {
+				i++
+				b.WriteRune(rs[i])
+				continue
+			}
+			if rs[i] == '"' {
+				closed = true
+				break
+			}
+			b.WriteRune(rs[i])
+		}
+		if !closed {
+			return nil, nil, fmt.Errorf("unterminated quote")
+		}
+		words = append(words, b.String())
+		quoted = append(quoted, true)
+	}
+	return words, quoted, nil
+}
+
+// ParseInterval parses a line of data files
+func ParseInterval(line string) (*Interval, error) {
+	words, quoted, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) < 2 || words[0] != "inc" {
+		return nil, fmt.Errorf("interval must start with inc")
+	}
+
+	in := &Interval{}
+	in.Start, err = time.Parse(timeFormat, words[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time [%s]", words[1])
+	}
+
+	words, quoted = words[2:], quoted[2:]
+	if len(words) >= 2 && words[0] == "-" && !quoted[0] {
+		in.End, err = time.Parse(timeFormat, words[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid end time [%s]", words[1])
+		}
+		if !in.Start.Before(in.End) {
+			return nil, fmt.Errorf("end time (%v) must be after start time (%v)", in.End, in.Start)
+		}
+		words, quoted = words[2:], quoted[2:]
+	}
+
+	if len(words) == 0 {
+		return in, nil
+	}
+	if words[0] != "#" || quoted[0] {
+		return nil, fmt.Errorf("unexpected word [%s]", words[0])
+	}
+	for i := 1; i < len(words); i++ {
+		if words[i] == "#" && !quoted[i] {
+			if i+1 < len(words) {
+				in.Annotation = strings.Join(words[i+1:], " ")
+			}
+			break
+		}
+		in.Tags = append(in.Tags, words[i])
+	}
+
+	return in, nil
+}
+
+// quote quotes a word if needed
+func quote(s string) string {
+	if s != "" && s != "#" && s != "-" && !strings.ContainsAny(s, " \"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// String returns the interval in the format of data files
+func (in *Interval) String() string {
+	s := "inc " + in.Start.UTC().Format(timeFormat)
+	if !in.End.IsZero() {
+		s += " - " + in.End.UTC().Format(timeFormat)
+	}
+	if len(in.Tags) == 0 && in.Annotation == "" {
+		return s
+	}
+
+	s += " #"
+	for _, v := range in.Tags {
+		s += " " + quote(v)
+	}
+	if in.Annotation != "" {
+		s += ` # "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(in.Annotation) + `"`
+	}
+	return s
+}
+
+// Read reads intervals in data files in specified directory, that is usually ~/.timewarrior/data.
+// Error is returned with the file name and line number if any of intervals is invalid.
+func Read(dir string) ([]*Interval, error) {
+	fs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*Interval{}
+	for _, v := range fs {
+		if v.IsDir() || !dataFile.MatchString(v.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, v.Name())
+		ins, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ins...)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Start.Before(ret[j].Start)
+	})
+
+	return ret, nil
+}
+
+func readFile(path string) ([]*Interval, error) {
+	f, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	ret := []*Interval{}
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		in, err := ParseInterval(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		ret = append(ret, in)
+	}
+
+	return ret, s.Err()
+}
+
+// Write writes intervals into data files of each month in specified directory.
+// Existing data files are not overwritten unless force is true, and nothing is written
+// if any of data files to write exists, so that data of Timewarrior is not lost.
+func Write(dir string, ins []*Interval, force bool) error {
+	files := map[string][]*Interval{}
+	names := []string{}
+	for _, v := range ins {
+		name := v.Start.UTC().Format("2006-01") + ".data"
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+		files[name] = append(files[name], v)
+	}
+	sort.Strings(names)
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		existing := []string{}
+		for _, name := range names {
+			_, err := os.Stat(filepath.Join(dir, name))
+			if err == nil {
+				existing = append(existing, name)
+				continue
+			}
+			if !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("data files [%s] already exist in %s", strings.Join(existing, ", "), dir)
+		}
+	}
+
+	for _, name := range names {
+		f, err := os.OpenFile(filepath.Join(dir, name), flag, 0644) // #nosec
+		if err != nil {
+			return err
+		}
+		err = WriteIntervals(f, files[name])
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		err = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteIntervals writes intervals in the format of data files ordered by start time
+func WriteIntervals(w io.Writer, ins []*Interval) error {
+	sorted := make([]*Interval, len(ins))
+	copy(sorted, ins)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	for _, v := range sorted {
+		if _, err := fmt.Fprintln(w, v.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToExport converts intervals into kokizami's Export document to import.
+// Each interval becomes a kizami, whose desc is the annotation followed by #tags.
+func ToExport(ins []*Interval) *kokizami.Export {
+	e := &kokizami.Export{
+		Version: kokizami.ExportVersion,
+		Kizamis: make([]kokizami.ExportKizami, len(ins)),
+		Tags:    []string{},
+	}
+
+	for i, v := range ins {
+		desc := kokizami.ComposeDesc(v.Annotation, v.Tags)
+
+		ek := kokizami.ExportKizami{
+			ID:        i + 1,
+			Desc:      desc,
+			StartedAt: v.Start.UTC(),
+			Tags:      kokizami.ExtractTags(desc),
+		}
+		if !v.End.IsZero() {
+			end := v.End.UTC()
+			ek.StoppedAt = &end
+		}
+		e.Kizamis[i] = ek
+	}
+
+	return e
+}
+
+// FromExport converts kizamis in kokizami's Export document into intervals.
+// Tags of kizamis are mapped to tags without "#" and the rest of desc is mapped to annotation.
+// A kizami that has been paused is converted into intervals of each on-going term.
+func FromExport(e *kokizami.Export) []*Interval {
+	ret := []*Interval{}
+	for _, v := range e.Kizamis {
+		tags := []string{}
+		for _, t := range v.Tags {
+			tags = append(tags, strings.TrimPrefix(t, "#"))
+		}
+		// Timewarrior sorts tags
+		sort.Strings(tags)
+
+		words := []string{}
+		for _, w := range strings.Split(v.Desc, " ") {
+			if len(kokizami.ExtractTags(w)) == 0 && w != "" {
+				words = append(words, w)
+			}
+		}
+		annotation := strings.Join(words, " ")
+
+		terms := v.Intervals
+		if len(terms) == 0 {
+			terms = []kokizami.ExportInterval{{StartedAt: v.StartedAt, StoppedAt: v.StoppedAt}}
+		}
+		for _, t := range terms {
+			in := &Interval{
+				Start:      t.StartedAt.UTC(),
+				Tags:       tags,
+				Annotation: annotation,
+			}
+			if t.StoppedAt != nil {
+				in.End = t.StoppedAt.UTC()
+			}
+			ret = append(ret, in)
+		}
+	}
+
+	return ret
+}
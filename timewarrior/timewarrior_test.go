@@ -0,0 +1,192 @@
+package timewarrior
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func at(h, m int) time.Time {
+	return time.Date(2019, 5, 1, h, m, 0, 0, time.UTC)
+}
+
+func TestParseInterval(t *testing.T) {
+	tcs := []struct {
+		in      string
+		want    *Interval
+		wantErr bool
+	}{
+		{
+			in:   "inc 20190501T090000Z - 20190501T100000Z",
+			want: &Interval{Start: at(9, 0), End: at(10, 0)},
+		},
+		{
+			in:   "inc 20190501T090000Z # code",
+			want: &Interval{Start: at(9, 0), Tags: []string{"code"}},
+		},
+		{
+			in: `inc 20190501T090000Z - 20190501T100000Z # code "client acme" # "review \"api\""`,
+			want: &Interval{
+				Start:      at(9, 0),
+				End:        at(10, 0),
+				Tags:       []string{"code", "client acme"},
+				Annotation: `review "api"`,
+			},
+		},
+		{
+			in:   `inc 20190501T090000Z - 20190501T100000Z # # "lunch"`,
+			want: &Interval{Start: at(9, 0), End: at(10, 0), Annotation: "lunch"},
+		},
+		{
+			// quoted # is a tag
+			in:   `inc 20190501T090000Z # "#"`,
+			want: &Interval{Start: at(9, 0), Tags: []string{"#"}},
+		},
+		{in: "exc 20190501T090000Z", wantErr: true},
+		{in: "inc 2019-05-01", wantErr: true},
+		{in: "inc 20190501T090000Z - 20190501T080000Z", wantErr: true},
+		{in: "inc 20190501T090000Z code", wantErr: true},
+		{in: `inc 20190501T090000Z # "code`, wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		got, err := ParseInterval(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+
+		// intervals are serialized to be parsed as they were
+		got, err = ParseInterval(got.String())
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timew")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ins := []*Interval{
+		{Start: at(9, 0).AddDate(0, 1, 0), Tags: []string{"code"}},
+		{Start: at(9, 0), End: at(10, 0), Tags: []string{"code"}, Annotation: "review"},
+	}
+	err = Write(dir, ins, false)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	// files other than data files of intervals are ignored
+	err = ioutil.WriteFile(filepath.Join(dir, "tags.data"), []byte("{}"), 0600)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2019-05.data")) // #nosec
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(string(b), "inc 20190501T090000Z - 20190501T100000Z # code # \"review\"\n"); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	got, err := Read(dir)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(got, []*Interval{ins[1], ins[0]}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// existing data files are not overwritten unless forced
+	err = Write(dir, ins[1:], false)
+	if err == nil || !strings.Contains(err.Error(), "2019-05.data") {
+		t.Fatalf("unexpected result: [got] %v [want] error on 2019-05.data", err)
+	}
+	err = Write(dir, []*Interval{{Start: at(11, 0), End: at(12, 0), Tags: []string{"doc"}}}, true)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "2019-05.data")) // #nosec
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(string(b), "inc 20190501T110000Z - 20190501T120000Z # doc\n"); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// file name and line number are reported
+	err = ioutil.WriteFile(filepath.Join(dir, "2019-07.data"), []byte("inc 20190701T090000Z\n\ninc broken\n"), 0600)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = Read(dir)
+	if err == nil || !strings.Contains(err.Error(), "2019-07.data:3:") {
+		t.Fatalf("unexpected result: [got] %v [want] error at 2019-07.data:3", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	stopped := at(10, 0)
+	e := &kokizami.Export{
+		Version: kokizami.ExportVersion,
+		Kizamis: []kokizami.ExportKizami{
+			{ID: 1, Desc: "review #client/acme #code", StartedAt: at(9, 0), StoppedAt: &stopped, Tags: []string{"#client/acme", "#code"}},
+			{ID: 2, Desc: "#meeting", StartedAt: at(11, 0), Tags: []string{"#meeting"}},
+		},
+	}
+
+	ins := FromExport(e)
+	want := []*Interval{
+		{Start: at(9, 0), End: at(10, 0), Tags: []string{"client/acme", "code"}, Annotation: "review"},
+		{Start: at(11, 0), Tags: []string{"meeting"}},
+	}
+	if diff := cmp.Diff(ins, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// kizamis are restored from intervals
+	got := ToExport(ins)
+	if diff := cmp.Diff(got.Kizamis, e.Kizamis); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// each term of paused kizamis becomes an interval
+	paused := at(11, 30)
+	e.Kizamis[1].Intervals = []kokizami.ExportInterval{
+		{StartedAt: at(11, 0), StoppedAt: &paused},
+		{StartedAt: at(12, 0)},
+	}
+	ins = FromExport(e)
+	if len(ins) != 3 || !ins[1].End.Equal(at(11, 30)) || !ins[2].End.IsZero() {
+		t.Fatalf("unexpected result: [got] %v [want] 3 intervals", ins)
+	}
+
+	got = ToExport([]*Interval{
+		{Start: at(9, 0), End: at(10, 0), Tags: []string{"client acme"}},
+		{Start: at(11, 0), End: at(12, 0)},
+	})
+	if got.Kizamis[0].Desc != "#client_acme" || got.Kizamis[1].Desc != kokizami.Untitled {
+		t.Fatalf("unexpected result: [got] %v [want] #client_acme and %s", got.Kizamis, kokizami.Untitled)
+	}
+}
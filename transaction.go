@@ -0,0 +1,68 @@
+package kokizami
+
+import "fmt"
+
+// Transaction represents a unit of work.
+// Repositories returned by Transaction operate in the transaction
+// until Commit or Rollback is called.
+type Transaction interface {
+	KizamiRepo() KizamiRepository
+	TagRepo() TagRepository
+	SummaryRepo() SummaryRepository
+	// SearchRepo returns nil if search is not available
+	SearchRepo() SearchRepository
+	EventRepo() EventRepository
+	Commit() error
+	Rollback() error
+}
+
+// TransactionRepository is an interface to begin a transaction
+type TransactionRepository interface {
+	Begin() (Transaction, error)
+}
+
+// Transaction calls fn in a transaction.
+// Kokizami passed to fn operates all of its repositories in the transaction,
+// so that summaries and search in fn see changes made in fn.
+// The transaction is committed if fn returns nil, otherwise rolled back.
+// fn is called with k itself if TxRepo is nil or k is already in a transaction.
+func (k *Kokizami) Transaction(fn func(tx *Kokizami) error) (err error) {
+	if k.TxRepo == nil {
+		return fn(k)
+	}
+
+	t, err := k.TxRepo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if e := t.Rollback(); e != nil && err == nil {
+			err = fmt.Errorf("failed to rollback transaction: %v", e)
+		}
+	}()
+
+	tx := *k
+	tx.TxRepo = nil
+	tx.KizamiRepo = t.KizamiRepo()
+	tx.TagRepo = t.TagRepo()
+	tx.SummaryRepo = t.SummaryRepo()
+	if k.SearchRepo != nil {
+		tx.SearchRepo = t.SearchRepo()
+	}
+	if k.EventRepo != nil {
+		tx.EventRepo = t.EventRepo()
+	}
+
+	err = fn(&tx)
+	if err != nil {
+		return err
+	}
+
+	committed = true
+	return t.Commit()
+}
@@ -0,0 +1,105 @@
+package kokizami
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func labelsOf(t *testing.T, k *Kokizami, kizamiID int) []string {
+	ts, err := k.TagsByKizamiID(kizamiID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ret := []string{}
+	for _, v := range ts {
+		ret = append(ret, v.Label)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestRetag(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge #a #b")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(labelsOf(t, k, ki.ID), []string{"#a", "#b"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ki.Desc = "hoge #b #c"
+	_, err = k.Edit(ki)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(labelsOf(t, k, ki.ID), []string{"#b", "#c"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = k.Delete(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(labelsOf(t, k, ki.ID), []string{}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge #a")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// changes are rolled back if fn returns an error
+	err = k.Transaction(func(tx *Kokizami) error {
+		if err := tx.Stop(ki.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Start("fuga #b"); err != nil {
+			return err
+		}
+		return fmt.Errorf("some error")
+	})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	ks, err := k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 1 || ks[0].StoppedAt.Unix() != 0 {
+		t.Fatalf("unexpected result: [got] %v [want] a running kizami", ks)
+	}
+	if diff := cmp.Diff(labelsOf(t, k, ki.ID), []string{"#a"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// changes are committed if fn returns nil
+	err = k.Transaction(func(tx *Kokizami) error {
+		if err := tx.Stop(ki.ID); err != nil {
+			return err
+		}
+		_, err := tx.Start("fuga #b")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ks, err = k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(ks), 2)
+	}
+}